@@ -0,0 +1,5 @@
+// +build race
+
+package mgo
+
+const raceDetector = true
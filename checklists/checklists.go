@@ -26,8 +26,8 @@ type Checklist struct {
 }
 
 // MakeReport runs all checks concurrently, and produces a user-facing string
-// summary of their run.
-func (chklst *Checklist) MakeReport() (anyFailed bool, report string) {
+// summary of their run, along with the number of checks that failed.
+func (chklst *Checklist) MakeReport() (failed int, report string) {
 	if chklst == nil { // pointers can always be nil
 		log.Warn("Nil checklist passed to makeReport. Please report this bug.")
 		return
@@ -55,7 +55,6 @@ func (chklst *Checklist) MakeReport() (anyFailed bool, report string) {
 	// aggregate statistics
 	total := len(chklst.Checks)
 	passed := 0
-	failed := 0
 	other := 0
 	for _ = range chklst.Checks {
 		code := <-codes
@@ -81,7 +80,7 @@ func (chklst *Checklist) MakeReport() (anyFailed bool, report string) {
 		}
 	}
 	close(msgs)
-	return (failed > 0), report
+	return failed, report
 }
 
 /***************** Checklist JSON structs *****************/
@@ -121,19 +120,7 @@ func ChecklistFromBytes(data []byte) (chklst Checklist, err error) {
 	defer close(out)
 	for _, chk := range chklstJSON.Checklist {
 		go func(chkJSON CheckJSON, out chan chkutil.Check) {
-			chkStruct := constructCheck(chkJSON)
-			if chkStruct == nil {
-				log.Fatal("Check had nil struct: " + chkJSON.ID)
-			}
-			newChk, err := chkStruct.New(chkJSON.Parameters)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"check":  chkJSON.ID,
-					"params": chkJSON.Parameters,
-					"error":  err.Error(),
-				}).Fatal("Error while constructing check")
-			}
-			out <- newChk
+			out <- constructCheck(chkJSON)
 		}(chk, out)
 	}
 	// grab all the data from the channel, mutating the checklist
@@ -0,0 +1,167 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zeldal/distributive/chkutil"
+	"github.com/zeldal/distributive/errutil"
+	"sync"
+)
+
+// subCheck is a constructed check, along with a readable description of the
+// specification it was constructed from
+type subCheck struct {
+	name string
+	chk  chkutil.Check
+}
+
+// parseSubChecks constructs a check from each parameter, each of which is a
+// JSON object in the same format as a checklist entry, e.g.
+// {"ID": "File", "Parameters": ["/etc/hosts"]}
+func parseSubChecks(params []string) (subs []subCheck, err error) {
+	if len(params) < 1 {
+		return subs, errutil.ParameterLengthError{1, params}
+	}
+	for _, param := range params {
+		var spec struct {
+			ID         string
+			Parameters []string
+		}
+		if err := json.Unmarshal([]byte(param), &spec); err != nil || spec.ID == "" {
+			return subs, errutil.ParameterTypeError{param, "check specification"}
+		}
+		chk, err := ConstructCheck(spec.ID, spec.Parameters)
+		if err != nil {
+			return subs, fmt.Errorf("Couldn't construct sub-check %s: %s", param, err)
+		}
+		name := chk.ID() + " " + fmt.Sprint(spec.Parameters)
+		subs = append(subs, subCheck{name: name, chk: chk})
+	}
+	return subs, nil
+}
+
+// runSubChecks runs all the given checks concurrently, and returns the names
+// of those that passed, and the names and messages of those that didn't. A
+// sub-check that returns an error or panics has failed. Sub-checks that log a
+// fatal error (like CommandOutputMatches, when its command can't be run) still
+// exit the whole process, since logrus exits without giving us a chance to
+// recover.
+func runSubChecks(subs []subCheck) (passed, failed []string) {
+	results := make([]string, len(subs))
+	ok := make([]bool, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub subCheck) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					results[i] = sub.name + ": panicked: " + fmt.Sprint(r)
+					ok[i] = false
+				}
+			}()
+			code, msg, err := sub.chk.Status()
+			results[i] = sub.name
+			if err != nil {
+				results[i] += ": " + err.Error()
+			} else if code != 0 && msg != "" {
+				results[i] += ": " + msg
+			}
+			ok[i] = code == 0 && err == nil
+		}(i, sub)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if ok[i] {
+			passed = append(passed, result)
+		} else {
+			failed = append(failed, result)
+		}
+	}
+	return passed, failed
+}
+
+// subCheckReport describes which sub-checks passed and which failed, one per
+// line
+func subCheckReport(msg string, passed, failed []string) string {
+	for _, name := range passed {
+		msg += "\n\tPassed: " + name
+	}
+	for _, name := range failed {
+		msg += "\n\tFailed: " + name
+	}
+	return msg
+}
+
+/*
+#### AllOf
+Description: Do all of these checks pass? Use with AnyOf to express
+conditions like "A and B, or C". A check that fails with an error is just a
+failed check, but one that would stop distributive on its own (by logging a
+fatal error) still does.
+Parameters:
+  - Checks ([]string): One or more checks, each a JSON object with an ID and
+    Parameters, in the same format as a checklist entry
+Example parameters:
+  - {"ID": "File", "Parameters": ["/etc/hosts"]}
+  - {"ID": "AnyOf", "Parameters": ["{\"ID\": \"Running\", \"Parameters\": [\"nginx\"]}"]}
+Dependencies:
+  - Those of each of the checks
+*/
+
+type AllOf struct{ checks []subCheck }
+
+func (chk AllOf) ID() string { return "AllOf" }
+
+func (chk AllOf) New(params []string) (chkutil.Check, error) {
+	subs, err := parseSubChecks(params)
+	if err != nil {
+		return chk, err
+	}
+	chk.checks = subs
+	return chk, nil
+}
+
+func (chk AllOf) Status() (int, string, error) {
+	passed, failed := runSubChecks(chk.checks)
+	if len(failed) == 0 {
+		return errutil.Success()
+	}
+	msg := fmt.Sprintf("%d of %d sub-checks failed", len(failed), len(chk.checks))
+	return 1, subCheckReport(msg, passed, failed), nil
+}
+
+/*
+#### AnyOf
+Description: Does at least one of these checks pass? As with AllOf, a check
+that would stop distributive on its own still does, even if another passes.
+Parameters:
+  - Checks ([]string): One or more checks, each a JSON object with an ID and
+    Parameters, in the same format as a checklist entry
+Example parameters:
+  - {"ID": "Port", "Parameters": ["80"]}, {"ID": "Port", "Parameters": ["443"]}
+Dependencies:
+  - Those of each of the checks
+*/
+
+type AnyOf struct{ checks []subCheck }
+
+func (chk AnyOf) ID() string { return "AnyOf" }
+
+func (chk AnyOf) New(params []string) (chkutil.Check, error) {
+	subs, err := parseSubChecks(params)
+	if err != nil {
+		return chk, err
+	}
+	chk.checks = subs
+	return chk, nil
+}
+
+func (chk AnyOf) Status() (int, string, error) {
+	passed, failed := runSubChecks(chk.checks)
+	if len(passed) > 0 {
+		return errutil.Success()
+	}
+	msg := fmt.Sprintf("All %d sub-checks failed", len(chk.checks))
+	return 1, subCheckReport(msg, passed, failed), nil
+}
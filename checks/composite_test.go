@@ -0,0 +1,139 @@
+package checks
+
+import (
+	"errors"
+	"github.com/zeldal/distributive/chkutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// stubCheck is a check that always returns the same status
+type stubCheck struct {
+	code int
+	msg  string
+	err  error
+}
+
+func (chk stubCheck) ID() string { return "Stub" }
+
+func (chk stubCheck) New(params []string) (chkutil.Check, error) { return chk, nil }
+
+func (chk stubCheck) Status() (int, string, error) {
+	return chk.code, chk.msg, chk.err
+}
+
+// panicCheck is a check with a bug
+type panicCheck struct{}
+
+func (chk panicCheck) ID() string { return "Panic" }
+
+func (chk panicCheck) New(params []string) (chkutil.Check, error) { return chk, nil }
+
+func (chk panicCheck) Status() (int, string, error) { panic("index out of range") }
+
+func TestRunSubChecks(t *testing.T) {
+	t.Parallel()
+	subs := []subCheck{
+		{"pass", stubCheck{0, "", nil}},
+		{"fail", stubCheck{1, "no good", nil}},
+		{"error", stubCheck{1, "", errors.New("broken")}},
+		{"pass again", stubCheck{0, "", nil}},
+		{"odd", stubCheck{0, "", errors.New("passed with an error")}},
+		{"panic", panicCheck{}},
+	}
+	passed, failed := runSubChecks(subs)
+	expectedPassed := []string{"pass", "pass again"}
+	expectedFailed := []string{
+		"fail: no good", "error: broken", "odd: passed with an error",
+		"panic: panicked: index out of range",
+	}
+	if strings.Join(passed, "|") != strings.Join(expectedPassed, "|") {
+		t.Errorf("Expected passed %q, got %q", expectedPassed, passed)
+	}
+	if strings.Join(failed, "|") != strings.Join(expectedFailed, "|") {
+		t.Errorf("Expected failed %q, got %q", expectedFailed, failed)
+	}
+}
+
+const (
+	passingSpec = `{"ID": "Directory", "Parameters": ["/"]}`
+	failingSpec = `{"ID": "File", "Parameters": ["/steppenwolf"]}`
+)
+
+var invalidSubChecks = [][]string{
+	{}, {""}, {"File"}, {`{"Parameters": ["/dev/null"]}`}, {"[]"},
+	{`{"ID": "steppenwolf", "Parameters": []}`},
+	{`{"ID": "File", "Parameters": []}`},
+	{passingSpec, `{"ID": "File", "Parameters": "/dev/null"}`},
+}
+
+func TestAllOf(t *testing.T) {
+	t.Parallel()
+	nested := `{"ID": "AnyOf", "Parameters": [` + strconv.Quote(failingSpec) + `, ` +
+		strconv.Quote(passingSpec) + `]}`
+	validInputs := [][]string{
+		{passingSpec}, {passingSpec, failingSpec}, {nested},
+		{`{"id": "directory", "parameters": ["/"]}`},
+	}
+	goodEggs := [][]string{
+		{passingSpec}, {passingSpec, passingSpec}, {nested, passingSpec},
+	}
+	badEggs := [][]string{
+		{failingSpec}, {passingSpec, failingSpec}, {failingSpec, passingSpec},
+	}
+	testParameters(validInputs, invalidSubChecks, AllOf{}, t)
+	testCheck(goodEggs, badEggs, AllOf{}, t)
+}
+
+func TestAnyOf(t *testing.T) {
+	t.Parallel()
+	nested := `{"ID": "AllOf", "Parameters": [` + strconv.Quote(failingSpec) + `, ` +
+		strconv.Quote(passingSpec) + `]}`
+	validInputs := [][]string{{passingSpec}, {failingSpec, failingSpec}, {nested}}
+	goodEggs := [][]string{
+		{passingSpec}, {failingSpec, passingSpec}, {nested, passingSpec},
+	}
+	badEggs := [][]string{{failingSpec}, {failingSpec, failingSpec}, {nested}}
+	testParameters(validInputs, invalidSubChecks, AnyOf{}, t)
+	testCheck(goodEggs, badEggs, AnyOf{}, t)
+}
+
+func TestFailingSubChecks(t *testing.T) {
+	t.Parallel()
+	// CommandMatchesGolden returns an error when its golden file can't be read
+	erroring := `{"ID": "CommandMatchesGolden", "Parameters": ["true", "/", ""]}`
+	anyOf, err := AnyOf{}.New([]string{erroring, passingSpec})
+	if err != nil {
+		t.Fatal(err)
+	} else if code, msg, err := anyOf.Status(); code != 0 || err != nil {
+		t.Errorf("AnyOf failed because of one erroring sub-check: %s %v", msg, err)
+	}
+	allOf, err := AllOf{}.New([]string{passingSpec, erroring})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, msg, err := allOf.Status()
+	if code == 0 || err != nil {
+		t.Errorf("AllOf passed with an erroring sub-check: %v", err)
+	} else if !strings.Contains(msg, "Failed: CommandMatchesGolden [true / ]: ") {
+		t.Errorf("AllOf didn't report the sub-check's error: %s", msg)
+	}
+}
+
+func TestSubCheckReport(t *testing.T) {
+	t.Parallel()
+	chk, err := AllOf{}.New([]string{passingSpec, failingSpec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, msg, _ := chk.Status()
+	for _, expected := range []string{
+		"1 of 2 sub-checks failed", "Passed: Directory [/]",
+		"Failed: File [/steppenwolf]",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Expected message to contain %q, got:\n%s", expected, msg)
+		}
+	}
+}
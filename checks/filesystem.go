@@ -1,15 +1,21 @@
 package checks
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/fsstatus"
 	"github.com/zeldal/distributive/tabular"
 	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type fileCondition func(path string) (bool, error)
@@ -163,6 +169,182 @@ func (chk Checksum) Status() (int, string, error) {
 	return errutil.GenericError(msg, chk.expectedChksum, []string{actualChksum})
 }
 
+// parseFileRegexp validates the parameters shared by FileMatches and
+// FileNotContains: the path to an existing file, and a regexp.
+func parseFileRegexp(params []string) (string, *regexp.Regexp, error) {
+	if len(params) != 2 {
+		return "", nil, errutil.ParameterLengthError{2, params}
+	}
+	re, err := regexp.Compile(params[1])
+	if err != nil {
+		return "", nil, errutil.ParameterTypeError{params[1], "regexp"}
+	}
+	path := params[0]
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, errutil.ParameterTypeError{path, "filepath"}
+	}
+	return path, re, nil
+}
+
+// fileLinesCheck is an abstraction of FileHeadMatches and FileTailMatches.
+// It reads the first (or last, if tail is set) n lines of the file at path,
+// and checks whether they match re.
+func fileLinesCheck(path string, n int, re *regexp.Regexp, tail bool) (int, string, error) {
+	readLines, which := fsstatus.HeadLines, "first"
+	if tail {
+		readLines, which = fsstatus.TailLines, "last"
+	}
+	lines, err := readLines(path, n)
+	if os.IsNotExist(err) {
+		return 1, "No such file: " + path, nil
+	} else if err != nil {
+		return 1, "", err
+	} else if re.MatchString(strings.Join(lines, "\n")) {
+		return errutil.Success()
+	}
+	msg := fmt.Sprintf("The %s %d lines of %s did not match", which, n, path)
+	return errutil.GenericError(msg, re.String(), lines)
+}
+
+// parseFileLinesRegexp validates the parameters shared by FileHeadMatches and
+// FileTailMatches: the path to an existing file, a line count, and a regexp.
+func parseFileLinesRegexp(params []string) (string, int, *regexp.Regexp, error) {
+	if len(params) != 3 {
+		return "", 0, nil, errutil.ParameterLengthError{3, params}
+	}
+	path, re, err := parseFileRegexp([]string{params[0], params[2]})
+	if err != nil {
+		return "", 0, nil, err
+	}
+	n, err := strconv.ParseUint(params[1], 10, 31)
+	if err != nil || n < 1 {
+		return "", 0, nil, errutil.ParameterTypeError{params[1], "positive int"}
+	}
+	return path, int(n), re, nil
+}
+
+/*
+#### FileHeadMatches
+Description: Do the first N lines of this file match this regexp? Only those
+lines are read, so this is cheap even for huge files.
+Parameters:
+  - Path (filepath): Path to file to check the contents of
+  - Lines (positive int): Number of lines to examine
+  - Regexp (regexp): Regexp to query the lines with
+Example parameters:
+  - /var/log/app.log, /etc/important-file.conf
+  - 1, 10
+  - "^# Managed by Puppet", "^#!/bin/(ba)?sh"
+*/
+
+type FileHeadMatches struct {
+	path  string
+	lines int
+	re    *regexp.Regexp
+}
+
+func (chk FileHeadMatches) ID() string { return "FileHeadMatches" }
+
+func (chk FileHeadMatches) New(params []string) (chkutil.Check, error) {
+	path, lines, re, err := parseFileLinesRegexp(params)
+	if err != nil {
+		return chk, err
+	}
+	chk.path, chk.lines, chk.re = path, lines, re
+	return chk, nil
+}
+
+func (chk FileHeadMatches) Status() (int, string, error) {
+	return fileLinesCheck(chk.path, chk.lines, chk.re, false)
+}
+
+/*
+#### FileTailMatches
+Description: Like FileHeadMatches, but for the last N lines of the file. The
+file is read backwards from its end.
+Example parameters:
+  - /var/log/backup.log, /var/log/app.log
+  - 1, 50
+  - "Backup completed successfully", "^</html>$"
+*/
+
+type FileTailMatches struct {
+	path  string
+	lines int
+	re    *regexp.Regexp
+}
+
+func (chk FileTailMatches) ID() string { return "FileTailMatches" }
+
+func (chk FileTailMatches) New(params []string) (chkutil.Check, error) {
+	path, lines, re, err := parseFileLinesRegexp(params)
+	if err != nil {
+		return chk, err
+	}
+	chk.path, chk.lines, chk.re = path, lines, re
+	return chk, nil
+}
+
+func (chk FileTailMatches) Status() (int, string, error) {
+	return fileLinesCheck(chk.path, chk.lines, chk.re, true)
+}
+
+/*
+#### FileStringCount
+Description: Does the number of occurrences of this literal string in this
+file satisfy this comparison? The file is streamed, so it can be large.
+Parameters:
+  - Path (filepath): Path to file to count occurrences in
+  - String (string): Literal string to count (non-overlapping) occurrences of
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare the count against
+Example parameters:
+  - /etc/nginx/nginx.conf, /etc/hosts
+  - "server {", "127.0.0.1"
+  - "==", ">="
+  - 1, 3
+*/
+
+type FileStringCount struct {
+	path, str, operator string
+	count               uint64
+}
+
+func (chk FileStringCount) ID() string { return "FileStringCount" }
+
+func (chk FileStringCount) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	} else if params[1] == "" {
+		return chk, errutil.ParameterTypeError{params[1], "non-empty string"}
+	} else if !chkutil.ValidOperator(params[2]) {
+		return chk, errutil.ParameterTypeError{params[2], "operator"}
+	}
+	count, err := strconv.ParseUint(params[3], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[3], "positive int"}
+	}
+	chk.path = params[0]
+	chk.str = params[1]
+	chk.operator = params[2]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk FileStringCount) Status() (int, string, error) {
+	actual, err := fsstatus.CountString(chk.path, chk.str)
+	if os.IsNotExist(err) {
+		return 1, "No such file: " + chk.path, nil
+	} else if err != nil {
+		return 1, "", err
+	} else if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := fmt.Sprintf("Found %d occurrences of %q in %s", actual, chk.str, chk.path)
+	expected := chk.operator + " " + fmt.Sprint(chk.count)
+	return errutil.GenericError(msg, expected, []string{fmt.Sprint(actual)})
+}
+
 /*
 #### FileMatches
 Description: Does this file match this regexp?
@@ -182,19 +364,12 @@ type FileMatches struct {
 func (chk FileMatches) ID() string { return "FileMatches" }
 
 func (chk FileMatches) New(params []string) (chkutil.Check, error) {
-	if len(params) != 2 {
-		return chk, errutil.ParameterLengthError{2, params}
-	}
-	re, err := regexp.Compile(params[1])
+	path, re, err := parseFileRegexp(params)
 	if err != nil {
-		return chk, errutil.ParameterTypeError{params[1], "regexp"}
-	}
-	chk.re = re
-	path := params[0]
-	if _, err := os.Stat(path); err != nil {
-		return chk, errutil.ParameterTypeError{path, "filepath"}
+		return chk, err
 	}
 	chk.path = path
+	chk.re = re
 	return chk, nil
 }
 
@@ -208,6 +383,57 @@ func (chk FileMatches) Status() (int, string, error) {
 	return 1, msg, nil
 }
 
+/*
+#### FileNotContains
+Description: The inverse of FileMatches: does this file *not* match this
+regexp?
+Parameters:
+  - Path (filepath): Path to file to check the contents of
+  - Regexp (regexp): Regexp that should not match anywhere in the file
+Example parameters:
+  - /etc/php.ini, /etc/config/important-file.conf
+  - "^display_errors\s*=\s*On", "debug=true", "password"
+*/
+
+type FileNotContains struct {
+	path string
+	re   *regexp.Regexp
+}
+
+func (chk FileNotContains) ID() string { return "FileNotContains" }
+
+func (chk FileNotContains) New(params []string) (chkutil.Check, error) {
+	path, re, err := parseFileRegexp(params)
+	if err != nil {
+		return chk, err
+	}
+	chk.path = path
+	chk.re = re
+	return chk, nil
+}
+
+func (chk FileNotContains) Status() (int, string, error) {
+	data := chkutil.FileToBytes(chk.path)
+	if !chk.re.Match(data) {
+		return errutil.Success()
+	}
+	msg := "File matches regexp:"
+	msg += "\n\tFile: " + chk.path
+	msg += "\n\tRegexp: " + chk.re.String()
+	reported := false
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if chk.re.Match(line) {
+			msg += "\n\tLine " + fmt.Sprint(i+1) + ": " + string(line)
+			reported = true
+		}
+	}
+	// the regexp might only match across several lines
+	if !reported {
+		msg += "\n\tMatch: " + string(chk.re.Find(data))
+	}
+	return 1, msg, nil
+}
+
 /*
 #### Permissions
 Description: Does this file have the given Permissions?
@@ -251,3 +477,614 @@ func (chk Permissions) Status() (int, string, error) {
 	}
 	return 1, "File did not have permissions: " + chk.expectedPerms, nil
 }
+
+/*
+#### GlobCount
+Description: Does the number of files matching this glob satisfy this
+comparison?
+Parameters:
+  - Glob (string): Pattern, as understood by filepath.Glob
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare the match count against
+Example parameters:
+  - "/var/spool/mqueue/qf*", "/srv/queue/*.job"
+  - "<", "==", ">="
+  - 0, 10, 500
+*/
+
+type GlobCount struct {
+	glob, operator string
+	count          uint64
+}
+
+func (chk GlobCount) ID() string { return "GlobCount" }
+
+func (chk GlobCount) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if _, err := filepath.Glob(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "glob"}
+	} else if !chkutil.ValidOperator(params[1]) {
+		return chk, errutil.ParameterTypeError{params[1], "operator"}
+	}
+	count, err := strconv.ParseUint(params[2], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[2], "positive int"}
+	}
+	chk.glob = params[0]
+	chk.operator = params[1]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk GlobCount) Status() (int, string, error) {
+	// the pattern was validated in New, so this can't err
+	matches, _ := filepath.Glob(chk.glob)
+	actual := uint64(len(matches))
+	if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := "Number of files matching " + chk.glob + " was " + fmt.Sprint(actual)
+	return errutil.GenericError(msg, chk.operator+" "+fmt.Sprint(chk.count), matches)
+}
+
+/*
+#### NewestFileAge
+Description: Was the most recently modified file in this directory (or matching
+this glob) modified within this duration?
+Parameters:
+  - Path (filepath or glob): Directory to look in, or glob to match files with
+  - Age (time.Duration): Maximum acceptable age of the newest file
+Example parameters:
+  - "/var/backups/", "/srv/exports/*.csv"
+  - 24h, 90m, 1h30m
+*/
+
+type NewestFileAge struct {
+	path   string
+	maxAge time.Duration
+}
+
+func (chk NewestFileAge) ID() string { return "NewestFileAge" }
+
+func (chk NewestFileAge) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if _, err := filepath.Glob(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "filepath or glob"}
+	}
+	maxAge, err := time.ParseDuration(params[1])
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[1], "time.Duration"}
+	}
+	chk.path = params[0]
+	chk.maxAge = maxAge
+	return chk, nil
+}
+
+func (chk NewestFileAge) Status() (int, string, error) {
+	// directories are searched one level deep
+	pattern := chk.path
+	if is, _ := fsstatus.IsDirectory(pattern); is {
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, _ := filepath.Glob(pattern) // validated in New
+	var newest string
+	var newestTime time.Time
+	for _, match := range matches {
+		finfo, err := os.Stat(match)
+		if err != nil || !finfo.Mode().IsRegular() {
+			continue
+		}
+		if finfo.ModTime().After(newestTime) {
+			newest = match
+			newestTime = finfo.ModTime()
+		}
+	}
+	if newest == "" {
+		return 1, "No files present: " + chk.path, nil
+	}
+	age := time.Since(newestTime)
+	if age <= chk.maxAge {
+		return errutil.Success()
+	}
+	msg := "Newest file is older than maximum age:"
+	msg += "\n\tFile: " + newest
+	msg += "\n\tAge: " + age.String()
+	msg += "\n\tMaximum: " + chk.maxAge.String()
+	return 1, msg, nil
+}
+
+// parseTimestamp parses a timestamp written by a heartbeat job: Unix seconds
+// (possibly fractional), RFC 3339, or "YYYY-MM-DD HH:MM:SS" in local time
+func parseTimestamp(str string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	if seconds, err := strconv.ParseFloat(str, 64); err == nil {
+		whole := int64(seconds)
+		return time.Unix(whole, int64((seconds-float64(whole))*1e9)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", str, time.Local)
+}
+
+/*
+#### Heartbeat
+Description: Has this heartbeat file been updated within this duration? It
+can optionally be required to contain a timestamp within the duration, too.
+Parameters:
+  - Path (filepath): Path to the heartbeat file
+  - Age (time.Duration): Maximum acceptable time since the last heartbeat
+  - Contents (string): "timestamp" to also check the time written in the file
+    (Unix seconds, RFC 3339, or "YYYY-MM-DD HH:MM:SS"), or "" to only check
+    when it was last modified
+Example parameters:
+  - "/var/run/myjob.heartbeat", "/srv/worker/alive"
+  - 1m, 5m, 1h
+  - "", "timestamp"
+*/
+
+type Heartbeat struct {
+	path          string
+	maxAge        time.Duration
+	readTimestamp bool
+}
+
+func (chk Heartbeat) ID() string { return "Heartbeat" }
+
+func (chk Heartbeat) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	}
+	maxAge, err := time.ParseDuration(params[1])
+	if err != nil || maxAge <= 0 {
+		return chk, errutil.ParameterTypeError{params[1], "time.Duration"}
+	}
+	switch strings.ToLower(params[2]) {
+	case "":
+		chk.readTimestamp = false
+	case "timestamp":
+		chk.readTimestamp = true
+	default:
+		return chk, errutil.ParameterTypeError{params[2], `"" | timestamp`}
+	}
+	chk.path = params[0]
+	chk.maxAge = maxAge
+	return chk, nil
+}
+
+func (chk Heartbeat) Status() (int, string, error) {
+	finfo, err := os.Stat(chk.path)
+	if os.IsNotExist(err) {
+		return 1, "Heartbeat file is missing: " + chk.path, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	// stale returns a failure message for a heartbeat of this age
+	stale := func(age time.Duration, source string) string {
+		msg := "Heartbeat is stale (" + source + "):"
+		msg += "\n\tFile: " + chk.path
+		msg += "\n\tAge: " + age.String()
+		msg += "\n\tMaximum: " + chk.maxAge.String()
+		return msg
+	}
+	if age := time.Since(finfo.ModTime()); age > chk.maxAge {
+		return 1, stale(age, "last modified"), nil
+	}
+	if chk.readTimestamp {
+		data, err := ioutil.ReadFile(chk.path)
+		if err != nil {
+			return 1, "", err
+		}
+		timestamp, err := parseTimestamp(string(data))
+		if err != nil {
+			msg := "Heartbeat file didn't contain a timestamp: " + chk.path
+			return 1, msg, nil
+		}
+		if age := time.Since(timestamp); age > chk.maxAge {
+			return 1, stale(age, "timestamp"), nil
+		}
+	}
+	return errutil.Success()
+}
+
+/*
+#### DirectorySize
+Description: Is the total size of the files under this directory below this
+amount? Entries that can't be read aren't counted, and are logged as a
+warning.
+Parameters:
+  - Path (filepath): Directory to measure, recursively
+  - Size (string with byte unit): Maximum acceptable total size
+  - Symlinks (string): follow | skip
+Example parameters:
+  - /var/log, /srv/uploads/
+  - 100mb, 1gb, 3TB, 20kib
+  - follow, skip
+*/
+
+// maxSkippedEntries is the most unreadable entries that DirectorySize lists
+const maxSkippedEntries = 10
+
+type DirectorySize struct {
+	path, maxSize string
+	maxBytes      uint64
+	follow        bool
+}
+
+func (chk DirectorySize) ID() string { return "DirectorySize" }
+
+func (chk DirectorySize) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	}
+	maxBytes, err := chkutil.ParseBytes(params[1])
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[1], "amount"}
+	}
+	switch strings.ToLower(params[2]) {
+	case "follow":
+		chk.follow = true
+	case "skip":
+		chk.follow = false
+	default:
+		return chk, errutil.ParameterTypeError{params[2], "follow | skip"}
+	}
+	chk.path = params[0]
+	chk.maxSize = params[1]
+	chk.maxBytes = maxBytes
+	return chk, nil
+}
+
+func (chk DirectorySize) Status() (int, string, error) {
+	if is, err := fsstatus.IsDirectory(chk.path); !is {
+		return isType("directory", fsstatus.IsDirectory, chk.path)
+	} else if err != nil {
+		return 1, "", err
+	}
+	actual, skipped, err := fsstatus.DirectorySize(chk.path, chk.follow)
+	if err != nil {
+		return 1, "", err
+	}
+	// list the unreadable entries, but don't let them fail the check
+	unreadable := len(skipped)
+	if unreadable > maxSkippedEntries {
+		skipped = append(skipped[:maxSkippedEntries], "...")
+	}
+	if actual < chk.maxBytes {
+		if unreadable > 0 {
+			log.WithFields(log.Fields{
+				"path":    chk.path,
+				"count":   unreadable,
+				"entries": strings.Join(skipped, ", "),
+			}).Warn("Couldn't read some entries, which weren't counted")
+		}
+		return errutil.Success()
+	}
+	msg := "Directory size exceeds defined maximum: " + chk.path
+	if unreadable > 0 {
+		msg += fmt.Sprintf("\nCouldn't read %d entries, which weren't counted: ",
+			unreadable)
+		msg += strings.Join(skipped, ", ")
+	}
+	return errutil.GenericError(msg, chk.maxSize, []string{fmt.Sprint(actual) + "b"})
+}
+
+/*
+#### FilesIdentical
+Description: Do these two files have exactly the same contents?
+Parameters:
+  - Path (filepath): Path to the first file
+  - Path (filepath): Path to the second file
+Example parameters:
+  - /etc/nginx/nginx.conf, /srv/mirror/index.html
+  - /mnt/backup/etc/nginx/nginx.conf, /srv/origin/index.html
+*/
+
+type FilesIdentical struct{ path1, path2 string }
+
+func (chk FilesIdentical) ID() string { return "FilesIdentical" }
+
+func (chk FilesIdentical) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	chk.path1 = params[0]
+	chk.path2 = params[1]
+	return chk, nil
+}
+
+func (chk FilesIdentical) Status() (int, string, error) {
+	var sizes []int64
+	for _, path := range []string{chk.path1, chk.path2} {
+		finfo, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return 1, "No such file: " + path, nil
+		} else if err != nil {
+			return 1, "", err
+		}
+		sizes = append(sizes, finfo.Size())
+	}
+	offset, err := fsstatus.FirstDifference(chk.path1, chk.path2)
+	if err != nil {
+		return 1, "", err
+	} else if offset < 0 {
+		return errutil.Success()
+	}
+	msg := "Files are not identical:"
+	if sizes[0] != sizes[1] {
+		msg = "Files differ in size:"
+	}
+	msg += "\n\t" + chk.path1 + ": " + fmt.Sprint(sizes[0]) + " bytes"
+	msg += "\n\t" + chk.path2 + ": " + fmt.Sprint(sizes[1]) + " bytes"
+	msg += "\n\tFirst difference at byte: " + fmt.Sprint(offset)
+	return 1, msg, nil
+}
+
+/*
+#### AllMountsHaveOption
+Description: Is every mounted filesystem of this type mounted with this option?
+Parameters:
+  - Type (string): Filesystem type, as in /proc/mounts
+  - Option (string): Required mount option
+Example parameters:
+  - ext4, tmpfs, nfs
+  - noexec, nosuid, nodev, ro
+Dependencies:
+  - /proc/mounts
+*/
+
+type AllMountsHaveOption struct{ fsType, option string }
+
+func (chk AllMountsHaveOption) ID() string { return "AllMountsHaveOption" }
+
+func (chk AllMountsHaveOption) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if strings.TrimSpace(params[1]) == "" || strings.Contains(params[1], ",") {
+		return chk, errutil.ParameterTypeError{params[1], "mount option"}
+	}
+	chk.fsType = params[0]
+	chk.option = params[1]
+	return chk, nil
+}
+
+func (chk AllMountsHaveOption) Status() (int, string, error) {
+	mounts, err := fsstatus.Mounts()
+	if err != nil {
+		return 1, "", err
+	}
+	var offenders []string
+	for _, mount := range mounts {
+		if mount.Type == chk.fsType && !mount.HasOption(chk.option) {
+			offenders = append(offenders, mount.MountPoint)
+		}
+	}
+	if len(offenders) == 0 {
+		return errutil.Success()
+	}
+	msg := "Some " + chk.fsType + " mounts lacked option " + chk.option + ":"
+	for _, offender := range offenders {
+		msg += "\n\t" + offender
+	}
+	return 1, msg, nil
+}
+
+// parseKeyValueLine parses a KEY=VALUE line like those in .env and INI files.
+// Blank lines and comments (starting with # or ;) aren't assignments, so ok is
+// false for them. An "export " prefix is ignored. Values may be quoted: single
+// quotes are taken literally, double quotes allow backslash escapes, and
+// unquoted values end at a " #" comment.
+func parseKeyValueLine(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == ';' {
+		return "", "", false
+	}
+	line = strings.TrimPrefix(line, "export ")
+	split := strings.SplitN(line, "=", 2)
+	if len(split) != 2 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(split[0])
+	value = strings.TrimSpace(split[1])
+	switch {
+	case len(value) >= 2 && value[0] == '\'':
+		if end := strings.Index(value[1:], "'"); end >= 0 {
+			return key, value[1 : end+1], true
+		}
+	case len(value) >= 2 && value[0] == '"':
+		// find the closing quote, skipping escaped ones
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\\' {
+				i++
+			} else if value[i] == '"' {
+				if unquoted, err := strconv.Unquote(value[:i+1]); err == nil {
+					return key, unquoted, true
+				}
+				return key, value[1:i], true
+			}
+		}
+	}
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return key, value, key != ""
+}
+
+/*
+#### DotenvVar
+Description: Is this variable set in this .env file, optionally to this value?
+If the variable is set more than once, the last value counts.
+Parameters:
+  - Path (filepath): Path to the .env file
+  - Name (string): Name of the variable
+  - Value (string): Expected value, or "" to only check that it's set
+Example parameters:
+  - "/srv/app/.env", "/etc/myapp/env"
+  - "DATABASE_URL", "RAILS_ENV"
+  - "", "production"
+*/
+
+type DotenvVar struct{ path, name, value string }
+
+func (chk DotenvVar) ID() string { return "DotenvVar" }
+
+func (chk DotenvVar) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if params[1] == "" || strings.ContainsAny(params[1], "= \t") {
+		return chk, errutil.ParameterTypeError{params[1], "variable name"}
+	}
+	chk.path = params[0]
+	chk.name = params[1]
+	chk.value = params[2]
+	return chk, nil
+}
+
+func (chk DotenvVar) Status() (int, string, error) {
+	data, err := ioutil.ReadFile(chk.path)
+	if os.IsNotExist(err) {
+		return 1, "No such file: " + chk.path, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	found := false
+	var actual string
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := parseKeyValueLine(line); ok && key == chk.name {
+			found, actual = true, value
+		}
+	}
+	if !found {
+		return 1, "Variable " + chk.name + " was not set in " + chk.path, nil
+	} else if chk.value == "" || actual == chk.value {
+		return errutil.Success()
+	}
+	msg := "Variable " + chk.name + " had an unexpected value in " + chk.path
+	return errutil.GenericError(msg, chk.value, []string{actual})
+}
+
+// zoneRecordTypes parses a DNS zone file (see RFC 1035, section 5) and returns
+// the type of each of its records, in upper case. Directives like $ORIGIN and
+// $TTL are skipped, and records continued over several lines in parentheses
+// are joined.
+func zoneRecordTypes(data string) (types []string) {
+	// scan removes a comment from a line, and counts the parentheses that open
+	// or close a multi-line record, ignoring any in quoted strings
+	scan := func(line string) (stripped string, depth int) {
+		quoted, escaped := false, false
+		for i, char := range line {
+			switch {
+			case escaped:
+				escaped = false
+			case char == '\\':
+				escaped = true
+			case char == '"':
+				quoted = !quoted
+			case quoted:
+			case char == ';':
+				return line[:i], depth
+			case char == '(':
+				depth++
+			case char == ')':
+				depth--
+			}
+		}
+		return line, depth
+	}
+	ttlRe := regexp.MustCompile(`^(\d+[smhdwSMHDW]?)+$`)
+	classes := []string{"IN", "CH", "HS", "CS"}
+	var record string
+	depth := 0
+	for _, line := range strings.Split(data, "\n") {
+		line, lineDepth := scan(line)
+		depth += lineDepth
+		if depth > 0 {
+			record += line + " "
+			continue
+		}
+		record, line = "", record+line
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "$") {
+			continue
+		}
+		line = strings.NewReplacer("(", " ", ")", " ").Replace(line)
+		fields := strings.Fields(line)
+		// lines that don't start with whitespace begin with an owner name
+		if line[0] != ' ' && line[0] != '\t' {
+			fields = fields[1:]
+		}
+		for len(fields) > 0 && (ttlRe.MatchString(fields[0]) ||
+			tabular.StrIn(strings.ToUpper(fields[0]), classes)) {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 {
+			types = append(types, strings.ToUpper(fields[0]))
+		}
+	}
+	return types
+}
+
+/*
+#### ZoneRecordCount
+Description: Does the number of records of this type in this DNS zone file
+satisfy this comparison?
+Parameters:
+  - Path (filepath): Path to the zone file
+  - Type (string): Record type, like A, AAAA, MX, or NS
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare the record count against
+Example parameters:
+  - "/etc/bind/db.example.com", "/var/named/example.com.zone"
+  - A, MX, NS
+  - ">=", "=="
+  - 1, 2, 50
+*/
+
+type ZoneRecordCount struct {
+	path, recordType, operator string
+	count                      uint64
+}
+
+func (chk ZoneRecordCount) ID() string { return "ZoneRecordCount" }
+
+func (chk ZoneRecordCount) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	} else if !regexp.MustCompile(`^[A-Za-z0-9]+$`).MatchString(params[1]) {
+		return chk, errutil.ParameterTypeError{params[1], "record type"}
+	} else if !chkutil.ValidOperator(params[2]) {
+		return chk, errutil.ParameterTypeError{params[2], "operator"}
+	}
+	count, err := strconv.ParseUint(params[3], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[3], "positive int"}
+	}
+	chk.path = params[0]
+	chk.recordType = strings.ToUpper(params[1])
+	chk.operator = params[2]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk ZoneRecordCount) Status() (int, string, error) {
+	data, err := ioutil.ReadFile(chk.path)
+	if os.IsNotExist(err) {
+		return 1, "No such file: " + chk.path, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	var actual uint64
+	for _, recordType := range zoneRecordTypes(string(data)) {
+		if recordType == chk.recordType {
+			actual++
+		}
+	}
+	if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := "Number of " + chk.recordType + " records in " + chk.path
+	msg += " was " + fmt.Sprint(actual)
+	expected := chk.operator + " " + fmt.Sprint(chk.count)
+	return errutil.GenericError(msg, expected, []string{fmt.Sprint(actual)})
+}
@@ -1,7 +1,12 @@
 package checks
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 var fileParameters = [][]string{
@@ -98,6 +103,80 @@ func TestFileMatches(t *testing.T) {
 	testCheck(goodEggs, badEggs, FileMatches{}, t)
 }
 
+func TestFileHeadMatches(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"/proc/cpuinfo", "1", ""}, {"/dev/null", "10", "str"}}
+	invalidInputs := [][]string{
+		{}, {"/dev/null"}, {"/dev/null", "1"}, {"/dev/null", "1", "", ""},
+		{"/notfile", "1", ""}, {"/dev/null", "0", ""}, {"/dev/null", "-1", ""},
+		{"/dev/null", "one", ""}, {"/dev/null", "1", "(str"},
+	}
+	goodEggs := [][]string{
+		{"/proc/self/status", "1", "^Name:"}, {"/proc/self/status", "5", "State:"},
+		{"/dev/null", "1", ""},
+	}
+	badEggs := [][]string{
+		{"/proc/self/status", "1", "State:"}, {"/dev/null", "1", "something"},
+	}
+	testParameters(validInputs, invalidInputs, FileHeadMatches{}, t)
+	testCheck(goodEggs, badEggs, FileHeadMatches{}, t)
+}
+
+func TestFileTailMatches(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"/proc/cpuinfo", "1", ""}, {"/dev/null", "10", "str"}}
+	invalidInputs := [][]string{
+		{}, {"/dev/null"}, {"/dev/null", "1"}, {"/dev/null", "1", "", ""},
+		{"/notfile", "1", ""}, {"/dev/null", "0", ""}, {"/dev/null", "1", "(str"},
+	}
+	goodEggs := [][]string{
+		{"/proc/self/status", "1", ":"}, {"/proc/self/status", "1000", "^Name:"},
+		{"/dev/null", "1", ""},
+	}
+	badEggs := [][]string{
+		{"/proc/self/status", "1", "^Name:"}, {"/dev/null", "1", "something"},
+	}
+	testParameters(validInputs, invalidInputs, FileTailMatches{}, t)
+	testCheck(goodEggs, badEggs, FileTailMatches{}, t)
+}
+
+func TestFileStringCount(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"/etc/hosts", "127.0.0.1", ">=", "1"}, {"/notfile", "a", "==", "0"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"/etc/hosts", "", ">=", "1"}, {"/etc/hosts", "a", "=>", "1"},
+		{"/etc/hosts", "a", ">=", "-1"}, {"/etc/hosts", "a", ">=", "one"},
+	}...)
+	goodEggs := [][]string{
+		{"/proc/self/status", "Name:", "==", "1"}, {"/dev/null", "a", "==", "0"},
+		{"/proc/self/status", "Uid:", "<=", "1"},
+	}
+	badEggs := [][]string{
+		{"/proc/self/status", "Name:", ">", "1"}, {"/dev/null", "a", ">=", "1"},
+		{"/steppenwolf", "a", "==", "0"},
+	}
+	testParameters(validInputs, invalidInputs, FileStringCount{}, t)
+	testCheck(goodEggs, badEggs, FileStringCount{}, t)
+}
+
+func TestFileNotContains(t *testing.T) {
+	t.Parallel()
+	validInputs := appendParameter(fileParameters, "")
+	invalidInputs := append(append(notLengthTwo, names...),
+		[][]string{{"/notfile", "notmatch"}, {"/dev/null", "["}}...)
+	goodEggs := [][]string{
+		{"/dev/null", "something"}, {"/proc/cpuinfo", "siddharthist"},
+	}
+	badEggs := [][]string{
+		{"/proc/cpuinfo", "processor"}, {"/proc/filesystems", `sysfs\s+nodev`},
+		{"/proc/filesystems", "^"},
+	}
+	testParameters(validInputs, invalidInputs, FileNotContains{}, t)
+	testCheck(goodEggs, badEggs, FileNotContains{}, t)
+}
+
 // $1 - path, $2 - givenMode (-rwxrwxrwx)
 func TestPermissions(t *testing.T) {
 	t.Parallel()
@@ -119,3 +198,261 @@ func TestPermissions(t *testing.T) {
 	testParameters(validInputs, invalidInputs, Permissions{}, t)
 	testCheck(goodEggs, badEggs, Permissions{}, t)
 }
+
+func TestGlobCount(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"/dev/*", ">", "0"}, {"/tmp/*.job", "==", "17"}, {"/nope", "<=", "1"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"[", ">", "1"}, {"/dev/*", "=>", "1"}, {"/dev/*", ">", "-1"},
+		{"/dev/*", ">", "one"},
+	}...)
+	goodEggs := [][]string{
+		{"/dev/*", ">", "0"}, {"/proc/cpuinfo", "==", "1"},
+		{"/steppenwolf/*", "==", "0"},
+	}
+	badEggs := [][]string{
+		{"/dev/*", "==", "0"}, {"/proc/cpuinfo", "!=", "1"},
+		{"/steppenwolf/*", ">", "0"},
+	}
+	testParameters(validInputs, invalidInputs, GlobCount{}, t)
+	testCheck(goodEggs, badEggs, GlobCount{}, t)
+}
+
+func TestNewestFileAge(t *testing.T) {
+	t.Parallel()
+	validInputs := append(appendParameter(dirParameters, "24h"),
+		appendParameter(fileParameters, "1µs")...)
+	invalidInputs := append(notLengthTwo,
+		[][]string{{"[", "1h"}, {"/tmp", "one day"}, {"/tmp", ""}}...)
+	goodEggs := [][]string{{"/proc/cpuinfo", "87600h"}, {"/proc", "87600h"}}
+	badEggs := [][]string{
+		{"/usr/bin", "1µs"}, {"/proc/*info", "1µs"}, {"/steppenwolf", "1h"},
+		{"/steppenwolf/*", "87600h"},
+	}
+	testParameters(validInputs, invalidInputs, NewestFileAge{}, t)
+	testCheck(goodEggs, badEggs, NewestFileAge{}, t)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	t.Parallel()
+	expected := time.Date(2016, 1, 7, 12, 0, 0, 0, time.UTC)
+	for _, str := range []string{"1452168000\n", "1452168000.0", "2016-01-07T12:00:00Z",
+		"2016-01-07T13:00:00+01:00"} {
+		if actual, err := parseTimestamp(str); err != nil {
+			t.Error(err)
+		} else if !actual.Equal(expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, str, actual)
+		}
+	}
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Error("Expected an error for a non-timestamp")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "heartbeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// writeHeartbeat writes contents to a new heartbeat file, modified at mtime
+	writeHeartbeat := func(name, contents string, mtime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		} else if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+	fresh := writeHeartbeat("fresh", fmt.Sprint(now.Unix()), now)
+	stale := writeHeartbeat("stale", fmt.Sprint(now.Unix()), now.Add(-time.Hour))
+	touched := writeHeartbeat("touched", now.Add(-time.Hour).Format(time.RFC3339), now)
+	empty := writeHeartbeat("empty", "", now)
+	validInputs := [][]string{{fresh, "1m", ""}, {"/steppenwolf", "1h", "TIMESTAMP"}}
+	invalidInputs := [][]string{
+		{}, {fresh}, {fresh, "1m"}, {fresh, "1m", "", ""}, {fresh, "1", ""},
+		{fresh, "0s", ""}, {fresh, "1m", "mtime"},
+	}
+	goodEggs := [][]string{
+		{fresh, "1m", ""}, {fresh, "1m", "timestamp"}, {touched, "1m", ""},
+		{empty, "1m", ""}, {stale, "2h", "timestamp"},
+	}
+	badEggs := [][]string{
+		{stale, "1m", ""}, {touched, "1m", "timestamp"}, {empty, "1m", "timestamp"},
+		{filepath.Join(dir, "missing"), "1h", ""},
+	}
+	testParameters(validInputs, invalidInputs, Heartbeat{}, t)
+	testCheck(goodEggs, badEggs, Heartbeat{}, t)
+}
+
+func TestDirectorySize(t *testing.T) {
+	t.Parallel()
+	validInputs := append(appendParameter(appendParameter(dirParameters,
+		"10gb"), "skip"), []string{"/steppenwolf", "1kb", "FOLLOW"})
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"/tmp", "big", "skip"}, {"/tmp", "1gb", "maybe"}, {"/tmp", "1gb", ""},
+	}...)
+	goodEggs := [][]string{
+		{"/usr/bin", "1tb", "skip"}, {"/usr/bin", "1tb", "follow"},
+	}
+	badEggs := [][]string{
+		{"/usr/bin", "1b", "skip"}, {"/usr/bin", "10kb", "follow"},
+		{"/steppenwolf", "1tb", "skip"}, {"/proc/cpuinfo", "1tb", "skip"},
+		{"/bin", "1tb", "follow"},
+	}
+	testParameters(validInputs, invalidInputs, DirectorySize{}, t)
+	testCheck(goodEggs, badEggs, DirectorySize{}, t)
+}
+
+func TestFilesIdentical(t *testing.T) {
+	t.Parallel()
+	validInputs := appendParameter(append(fileParameters, names...), "/dev/null")
+	invalidInputs := append(notLengthTwo, []string{"/dev/null"})
+	goodEggs := [][]string{
+		{"/dev/null", "/dev/null"}, {"/bin/bash", "/bin/bash"},
+		{"/proc/cpuinfo", "/proc/cpuinfo"},
+	}
+	badEggs := [][]string{
+		{"/bin/bash", "/dev/null"}, {"/proc/cpuinfo", "/proc/filesystems"},
+		{"/steppenwolf", "/dev/null"}, {"/dev/null", "/steppenwolf"},
+	}
+	testParameters(validInputs, invalidInputs, FilesIdentical{}, t)
+	testCheck(goodEggs, badEggs, FilesIdentical{}, t)
+}
+
+func TestAllMountsHaveOption(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"ext4", "noexec"}, {"tmpfs", "size"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"ext4", ""}, {"ext4", "noexec,nosuid"},
+	}...)
+	goodEggs := [][]string{
+		{"proc", "rw"}, {"lkjashldfb", "noexec"}, {"sysfs", "relatime"},
+	}
+	badEggs := [][]string{{"proc", "lkjashldfb"}, {"sysfs", "size=1k"}}
+	testParameters(validInputs, invalidInputs, AllMountsHaveOption{}, t)
+	testCheck(goodEggs, badEggs, AllMountsHaveOption{}, t)
+}
+
+func TestParseKeyValueLine(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		line, key, value string
+		ok               bool
+	}{
+		{"RAILS_ENV=production", "RAILS_ENV", "production", true},
+		{"export PORT = 8080 # the port", "PORT", "8080", true},
+		{`SECRET="a \"quoted\" # value"`, "SECRET", `a "quoted" # value`, true},
+		{`GREETING="hello\nworld"  # comment`, "GREETING", "hello\nworld", true},
+		{`RAW='no $escapes\n here'`, "RAW", `no $escapes\n here`, true},
+		{"EMPTY=", "EMPTY", "", true},
+		{"  # a comment", "", "", false},
+		{"; an INI comment", "", "", false},
+		{"", "", "", false},
+		{"[section]", "", "", false},
+	}
+	for _, test := range tests {
+		key, value, ok := parseKeyValueLine(test.line)
+		if key != test.key || value != test.value || ok != test.ok {
+			t.Errorf("Expected (%q, %q, %v), got (%q, %q, %v) for %q", test.key,
+				test.value, test.ok, key, value, ok, test.line)
+		}
+	}
+}
+
+func TestDotenvVar(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".env")
+	env := "# app config\nexport RAILS_ENV=staging\nDATABASE_URL=\"postgres://db/app\"\n" +
+		"RAILS_ENV=production\nEMPTY=\n"
+	if err := ioutil.WriteFile(path, []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+	validInputs := [][]string{{path, "RAILS_ENV", ""}, {"/srv/app/.env", "PORT", "80"}}
+	invalidInputs := [][]string{
+		{}, {path}, {path, "RAILS_ENV"}, {path, "RAILS_ENV", "", ""},
+		{path, "", ""}, {path, "RAILS_ENV=production", ""},
+	}
+	goodEggs := [][]string{
+		{path, "RAILS_ENV", "production"}, {path, "DATABASE_URL", "postgres://db/app"},
+		{path, "EMPTY", ""}, {path, "RAILS_ENV", ""},
+	}
+	badEggs := [][]string{
+		{path, "RAILS_ENV", "staging"}, {path, "PORT", ""},
+		{"/steppenwolf/.env", "RAILS_ENV", ""},
+	}
+	testParameters(validInputs, invalidInputs, DotenvVar{}, t)
+	testCheck(goodEggs, badEggs, DotenvVar{}, t)
+}
+
+var exampleZone = `$ORIGIN example.com.
+$TTL 3600
+@	IN	SOA	ns1.example.com. admin.example.com. (
+		2016010101 ; serial
+		7200       ; refresh
+		3600 1209600 3600 )
+	IN	NS	ns1
+	IN	NS	ns2
+	IN	MX	10 mail ; primary mail
+ns1	IN	A	192.0.2.1
+ns2	300	IN	A	192.0.2.2
+mail	A	192.0.2.3
+www	IN	AAAA	2001:db8::1
+paren	IN	TXT	"foo (bar"
+escaped	IN	TXT	"say \"(hi\" ; still quoted" ; a comment (with a paren
+multi	IN	TXT	( "part one )"
+		"part two" )
+txt	IN	TXT	"v=spf1 -all; not a comment"
+`
+
+func TestZoneRecordTypes(t *testing.T) {
+	t.Parallel()
+	expected := []string{
+		"SOA", "NS", "NS", "MX", "A", "A", "A", "AAAA", "TXT", "TXT", "TXT",
+		"TXT",
+	}
+	actual := zoneRecordTypes(exampleZone)
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestZoneRecordCount(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "db.example.com")
+	if err := ioutil.WriteFile(path, []byte(exampleZone), 0644); err != nil {
+		t.Fatal(err)
+	}
+	validInputs := [][]string{
+		{path, "A", "==", "3"}, {"/etc/bind/db.local", "mx", ">=", "0"},
+	}
+	invalidInputs := append(notLengthOne, [][]string{
+		{path, "A", "=", "3"}, {path, "A", "==", "-1"},
+		{path, "A AAAA", "==", "1"}, {path, "A", "==", "three"},
+	}...)
+	goodEggs := [][]string{
+		{path, "A", "==", "3"}, {path, "ns", ">=", "2"},
+		{path, "SOA", "==", "1"}, {path, "CNAME", "==", "0"},
+	}
+	badEggs := [][]string{
+		{path, "A", ">", "3"}, {path, "MX", "==", "2"},
+		{"/steppenwolf", "A", "==", "0"},
+	}
+	testParameters(validInputs, invalidInputs, ZoneRecordCount{}, t)
+	testCheck(goodEggs, badEggs, ZoneRecordCount{}, t)
+}
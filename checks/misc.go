@@ -1,18 +1,69 @@
 package checks
 
 import (
+	"errors"
 	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/tabular"
 	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"math"
+	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
+// exitCode extracts the exit code from the error returned by running an
+// exec.Cmd. It never returns 0 for a non-nil error.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var code int
+	// this is convoluted, but should work on Windows & Unix
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+			code = status.ExitStatus()
+		}
+	}
+	// dummy, in case the above failed. We know it's not zero!
+	if code == 0 {
+		code = 1
+	}
+	return code
+}
+
+// shellBuiltins are bash's builtins and keywords, which can be run as commands
+// even though they aren't executables
+var shellBuiltins = []string{
+	"!", ".", ":", "[", "[[", "alias", "bg", "bind", "break", "builtin",
+	"case", "cd", "command", "compgen", "complete", "continue", "declare",
+	"dirs", "disown", "echo", "enable", "eval", "exec", "exit", "export",
+	"false", "fc", "fg", "for", "function", "getopts", "hash", "help",
+	"history", "if", "jobs", "kill", "let", "local", "logout", "popd",
+	"printf", "pushd", "pwd", "read", "readonly", "return", "select", "set",
+	"shift", "shopt", "source", "test", "time", "times", "trap", "true",
+	"type", "typeset", "ulimit", "umask", "unalias", "unset", "until",
+	"wait", "while",
+}
+
+// commandExecutable returns the executable that this shell command runs
+// first, or "" if that can't be known without running it, e.g. because it
+// starts with a builtin, a variable assignment, or other shell syntax.
+func commandExecutable(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) < 1 || tabular.StrIn(fields[0], shellBuiltins) ||
+		strings.ContainsAny(fields[0], "$`'\"\\=(){}<>|&;*?~") {
+		return ""
+	}
+	return fields[0]
+}
+
 /*
 #### Command
 Description: Does this Command exit without error?
@@ -27,10 +78,15 @@ type Command struct{ Command string }
 func (chk Command) ID() string { return "Command" }
 
 func (chk Command) New(params []string) (chkutil.Check, error) {
-	// TODO further validation with LookPath - maybe in parameter-validation.go
 	if len(params) != 1 {
 		return chk, errutil.ParameterLengthError{1, params}
 	}
+	if name := commandExecutable(params[0]); name != "" {
+		if _, err := exec.LookPath(name); err != nil {
+			expected := "command (" + name + " not found in $PATH)"
+			return chk, errutil.ParameterTypeError{params[0], expected}
+		}
+	}
 	chk.Command = params[0]
 	return chk, nil
 }
@@ -44,27 +100,48 @@ func (chk Command) Status() (int, string, error) {
 		return 1, "", err
 	}
 	if err = cmd.Wait(); err != nil {
-		var exitCode int
-		// this is convoluted, but should work on Windows & Unix
-		if exiterr, ok := err.(*exec.ExitError); ok {
-			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-				exitCode = status.ExitStatus()
-			}
-		}
-		// dummy, in case the above failed. We know it's not zero!
-		if exitCode == 0 {
-			exitCode = 1
-		}
 		out, _ := cmd.CombinedOutput() // don't care if this fails
 		exitMessage := "Command exited with non-zero exit code:"
 		exitMessage += "\n\tCommand: " + chk.Command
-		exitMessage += "\n\tExit code: " + fmt.Sprint(exitCode)
+		exitMessage += "\n\tExit code: " + fmt.Sprint(exitCode(err))
 		exitMessage += "\n\tOutput: " + string(out)
 		return 1, exitMessage, nil
 	}
 	return errutil.Success()
 }
 
+/*
+#### ExecutableInPath
+Description: Is there an executable by this name in the $PATH? On success,
+reports the path it resolves to.
+Parameters:
+  - Name (string): Name of the executable
+Example parameters:
+  - "bash", "docker", "python3"
+*/
+
+type ExecutableInPath struct{ name string }
+
+func (chk ExecutableInPath) ID() string { return "ExecutableInPath" }
+
+func (chk ExecutableInPath) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	} else if params[0] == "" {
+		return chk, errutil.ParameterTypeError{params[0], "executable name"}
+	}
+	chk.name = params[0]
+	return chk, nil
+}
+
+func (chk ExecutableInPath) Status() (int, string, error) {
+	path, err := exec.LookPath(chk.name)
+	if err != nil {
+		return 1, "Executable not found in $PATH: " + chk.name, nil
+	}
+	return 0, "Found executable " + chk.name + " at " + path, nil
+}
+
 /*
 #### CommandOutputMatches
 Description: Does the combined (stdout + stderr) output of this Command match
@@ -110,6 +187,614 @@ func (chk CommandOutputMatches) Status() (int, string, error) {
 	return errutil.GenericError(msg, chk.re.String(), []string{string(out)})
 }
 
+/*
+#### CommandOutputMatchCount
+Description: Does the number of non-overlapping matches of this regexp in the
+combined (stdout + stderr) output of this Command satisfy this comparison?
+Parameters:
+  - Cmd (string): Command to be executed
+  - Regexp (regexp): Regexp to count matches of
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare the match count against
+Example parameters:
+  - "who", "cat /var/lib/myapp/sessions.dump"
+  - "^root ", "state=active"
+  - "==", "<="
+  - 0, 3
+*/
+
+type CommandOutputMatchCount struct {
+	Command  string
+	re       *regexp.Regexp
+	operator string
+	count    uint64
+}
+
+func (chk CommandOutputMatchCount) ID() string { return "CommandOutputMatchCount" }
+
+func (chk CommandOutputMatchCount) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	}
+	re, err := regexp.Compile(params[1])
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[1], "regexp"}
+	} else if !chkutil.ValidOperator(params[2]) {
+		return chk, errutil.ParameterTypeError{params[2], "operator"}
+	}
+	count, err := strconv.ParseUint(params[3], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[3], "positive int"}
+	}
+	chk.Command = params[0]
+	chk.re = re
+	chk.operator = params[2]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk CommandOutputMatchCount) Status() (int, string, error) {
+	cmd := exec.Command("bash", "-c", chk.Command)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		errutil.ExecError(cmd, string(out), err)
+	}
+	actual := len(chk.re.FindAllString(string(out), -1))
+	if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := "Command output had " + fmt.Sprint(actual) + " matches of regexp "
+	msg += chk.re.String()
+	expected := chk.operator + " " + fmt.Sprint(chk.count)
+	return errutil.GenericError(msg, expected, []string{fmt.Sprint(actual)})
+}
+
+// commandNumber runs this command and parses its (trimmed) standard output as
+// a number. The message describes any failure, for use in check results.
+func commandNumber(command string) (float64, string) {
+	out, err := exec.Command("bash", "-c", command).Output()
+	if err != nil {
+		msg := "Command exited with non-zero exit code:"
+		msg += "\n\tCommand: " + command
+		msg += "\n\tExit code: " + fmt.Sprint(exitCode(err))
+		return 0, msg
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		msg := "Command output was not a number:"
+		msg += "\n\tCommand: " + command
+		msg += "\n\tOutput: " + string(out)
+		return 0, msg
+	}
+	return value, ""
+}
+
+/*
+#### CommandOutputsCompare
+Description: Does the number output by the first Command compare to the number
+output by the second in this way? Values within the tolerance of each other
+are considered equal.
+Parameters:
+  - Cmd1 (string): Command whose output is the left hand side
+  - Cmd2 (string): Command whose output is the right hand side
+  - Operator (string): < | <= | == | != | >= | >
+  - Tolerance (float): Largest difference still considered equal
+Example parameters:
+  - "df --output=avail -B1 /backup | tail -1"
+  - "du -sb /var/lib/db | cut -f1"
+  - ">", ">="
+  - 0, 0.5, 1024
+*/
+
+type CommandOutputsCompare struct {
+	command1, command2, operator string
+	tolerance                    float64
+}
+
+func (chk CommandOutputsCompare) ID() string { return "CommandOutputsCompare" }
+
+func (chk CommandOutputsCompare) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	} else if !chkutil.ValidOperator(params[2]) {
+		return chk, errutil.ParameterTypeError{params[2], "operator"}
+	}
+	tolerance, err := strconv.ParseFloat(params[3], 64)
+	if err != nil || tolerance < 0 {
+		return chk, errutil.ParameterTypeError{params[3], "positive float"}
+	}
+	chk.command1 = params[0]
+	chk.command2 = params[1]
+	chk.operator = params[2]
+	chk.tolerance = tolerance
+	return chk, nil
+}
+
+func (chk CommandOutputsCompare) Status() (int, string, error) {
+	value1, msg := commandNumber(chk.command1)
+	if msg != "" {
+		return 1, msg, nil
+	}
+	value2, msg := commandNumber(chk.command2)
+	if msg != "" {
+		return 1, msg, nil
+	}
+	difference := value1 - value2
+	if math.Abs(difference) <= chk.tolerance {
+		difference = 0
+	}
+	if chkutil.Compare(chk.operator, difference, 0) {
+		return errutil.Success()
+	}
+	msg = "Command outputs did not compare as expected:"
+	msg += "\n\tFirst: " + fmt.Sprint(value1) + " (" + chk.command1 + ")"
+	msg += "\n\tSecond: " + fmt.Sprint(value2) + " (" + chk.command2 + ")"
+	msg += "\n\tExpected: first " + chk.operator + " second, within "
+	msg += fmt.Sprint(chk.tolerance)
+	return 1, msg, nil
+}
+
+// maxDiffCells bounds the memory lineDiff uses, which is proportional to the
+// product of the numbers of lines in each input that are left to compare
+const maxDiffCells = 1 << 20
+
+// lineDiff returns the lines that differ between old and new, prefixed by
+// "-" if they're only in old and "+" if they're only in new, in the order of a
+// longest common subsequence of the two. Common leading and trailing lines are
+// skipped first. If what's left is still too large to diff, only its first
+// lines are returned, and complete is false.
+func lineDiff(old, new []string) (diff []string, complete bool) {
+	for len(old) > 0 && len(new) > 0 && old[0] == new[0] {
+		old, new = old[1:], new[1:]
+	}
+	for len(old) > 0 && len(new) > 0 && old[len(old)-1] == new[len(new)-1] {
+		old, new = old[:len(old)-1], new[:len(new)-1]
+	}
+	if len(old)*len(new) > maxDiffCells {
+		return []string{"-" + old[0], "+" + new[0]}, false
+	}
+	// lcs[i][j] is the length of the longest common subsequence of old[i:]
+	// and new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			i, j = i+1, j+1
+		case j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+old[i])
+			i++
+		default:
+			diff = append(diff, "+"+new[j])
+			j++
+		}
+	}
+	return diff, true
+}
+
+// normalizeLines splits str into lines without trailing whitespace, dropping
+// trailing blank lines and any lines matching ignore (if it isn't nil)
+func normalizeLines(str string, ignore *regexp.Regexp) (lines []string) {
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if ignore == nil || !ignore.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+/*
+#### CommandMatchesGolden
+Description: Does this Command exit without error, and is its combined (stdout
++ stderr) output the same as the contents of this golden file? Trailing
+whitespace is ignored, as are lines matching the optional regexp.
+Parameters:
+  - Cmd (string): Command to be executed
+  - Golden (filepath): Path to the file with the expected output
+  - Ignore (regexp): Regexp for volatile lines to ignore, or "" for none
+Example parameters:
+  - "iptables-save", "sysctl -a 2>/dev/null | sort"
+  - "/etc/distributive/golden/iptables", "/srv/golden/sysctl.txt"
+  - "", "^#", "^# (Generated|Completed) on"
+*/
+
+type CommandMatchesGolden struct {
+	Command, golden string
+	ignore          *regexp.Regexp
+}
+
+func (chk CommandMatchesGolden) ID() string { return "CommandMatchesGolden" }
+
+func (chk CommandMatchesGolden) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	}
+	chk.ignore = nil
+	if params[2] != "" {
+		re, err := regexp.Compile(params[2])
+		if err != nil {
+			return chk, errutil.ParameterTypeError{params[2], "regexp"}
+		}
+		chk.ignore = re
+	}
+	chk.Command = params[0]
+	chk.golden = params[1]
+	return chk, nil
+}
+
+func (chk CommandMatchesGolden) Status() (int, string, error) {
+	golden, err := ioutil.ReadFile(chk.golden)
+	if os.IsNotExist(err) {
+		return 1, "No such golden file: " + chk.golden, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	out, err := exec.Command("bash", "-c", chk.Command).CombinedOutput()
+	if _, ok := err.(*exec.ExitError); ok {
+		msg := "Command exited with code " + fmt.Sprint(exitCode(err))
+		msg += ": " + chk.Command + "\n\tOutput: " + string(out)
+		return 1, msg, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	expected := normalizeLines(string(golden), chk.ignore)
+	actual := normalizeLines(string(out), chk.ignore)
+	diff, complete := lineDiff(expected, actual)
+	if len(diff) < 1 {
+		return errutil.Success()
+	}
+	msg := "Command output differed from golden file:"
+	msg += "\n\tCommand: " + chk.Command
+	msg += "\n\tGolden file: " + chk.golden
+	if complete {
+		msg += "\n\tDiff (- golden, + actual):"
+	} else {
+		msg += "\n\tFirst difference (- golden, + actual), too large to diff:"
+	}
+	for i, line := range diff {
+		if i == 50 {
+			msg += "\n\t... " + fmt.Sprint(len(diff)-i) + " more lines"
+			break
+		}
+		msg += "\n\t" + line
+	}
+	return 1, msg, nil
+}
+
+/*
+#### CommandStable
+Description: Does this Command exit without error every time, when run this
+many times in a row? Catches intermittent failures that a single run would miss.
+Parameters:
+  - Cmd (string): Command to be executed
+  - Runs (positive int): Number of times to run it
+Example parameters:
+  - "curl -sf http://localhost:8080/health", "/bin/my_flaky_check.py"
+  - 3, 5, 10
+*/
+
+type CommandStable struct {
+	Command string
+	runs    int
+}
+
+func (chk CommandStable) ID() string { return "CommandStable" }
+
+func (chk CommandStable) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	runs, err := strconv.ParseUint(params[1], 10, 16)
+	if err != nil || runs < 1 {
+		return chk, errutil.ParameterTypeError{params[1], "positive int"}
+	}
+	chk.Command = params[0]
+	chk.runs = int(runs)
+	return chk, nil
+}
+
+func (chk CommandStable) Status() (int, string, error) {
+	failures := []string{}
+	for i := 1; i <= chk.runs; i++ {
+		out, err := exec.Command("bash", "-c", chk.Command).CombinedOutput()
+		if err != nil {
+			failure := "Run " + fmt.Sprint(i)
+			failure += " (exit code " + fmt.Sprint(exitCode(err)) + "): "
+			failures = append(failures, failure+string(out))
+		}
+	}
+	if len(failures) == 0 {
+		return errutil.Success()
+	}
+	msg := "Command failed on " + fmt.Sprint(len(failures)) + " of "
+	msg += fmt.Sprint(chk.runs) + " runs:"
+	msg += "\n\tCommand: " + chk.Command
+	for _, failure := range failures {
+		msg += "\n\t" + failure
+	}
+	return 1, msg, nil
+}
+
+/*
+#### CommandOutputSorted
+Description: Are the lines of the combined (stdout + stderr) output of this
+Command sorted in this direction? asc and desc compare lines byte by byte, so
+"1.10" comes before "1.9". version-asc and version-desc compare runs of digits
+as numbers, so "1.9" comes before "1.10".
+Parameters:
+  - Cmd (string): Command to be executed
+  - Direction (string): asc | desc | version-asc | version-desc
+Example parameters:
+  - "ls /opt/releases/", "cat /var/lib/myapp/versions"
+  - asc, desc, version-asc
+*/
+
+type CommandOutputSorted struct {
+	Command    string
+	descending bool
+	versions   bool
+}
+
+// compareVersions compares two version strings, returning -1, 0, or 1. Runs
+// of digits are compared as numbers, and everything else byte by byte.
+func compareVersions(a, b string) int {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	// run returns the leading run of digits or non-digits in str
+	run := func(str string) string {
+		i := 1
+		for i < len(str) && isDigit(str[i]) == isDigit(str[0]) {
+			i++
+		}
+		return str[:i]
+	}
+	for a != "" && b != "" {
+		runA, runB := run(a), run(b)
+		a, b = a[len(runA):], b[len(runB):]
+		if isDigit(runA[0]) && isDigit(runB[0]) {
+			// compare the numbers' lengths without leading zeros, and then
+			// their digits, so that any size of number can be compared
+			numA, numB := strings.TrimLeft(runA, "0"), strings.TrimLeft(runB, "0")
+			if len(numA) != len(numB) {
+				if len(numA) < len(numB) {
+					return -1
+				}
+				return 1
+			}
+			runA, runB = numA, numB
+		}
+		if runA < runB {
+			return -1
+		} else if runA > runB {
+			return 1
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func (chk CommandOutputSorted) ID() string { return "CommandOutputSorted" }
+
+func (chk CommandOutputSorted) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	direction := strings.ToLower(params[1])
+	chk.versions = strings.HasPrefix(direction, "version-")
+	switch strings.TrimPrefix(direction, "version-") {
+	case "asc":
+		chk.descending = false
+	case "desc":
+		chk.descending = true
+	default:
+		return chk, errutil.ParameterTypeError{params[1],
+			"asc | desc | version-asc | version-desc"}
+	}
+	chk.Command = params[0]
+	return chk, nil
+}
+
+func (chk CommandOutputSorted) Status() (int, string, error) {
+	cmd := exec.Command("bash", "-c", chk.Command)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		errutil.ExecError(cmd, string(out), err)
+	}
+	lines := tabular.Lines(strings.TrimSuffix(string(out), "\n"))
+	for i := 1; i < len(lines); i++ {
+		prev, next := lines[i-1], lines[i]
+		outOfOrder := (!chk.descending && prev > next) || (chk.descending && prev < next)
+		if chk.versions {
+			order := compareVersions(prev, next)
+			outOfOrder = (!chk.descending && order > 0) || (chk.descending && order < 0)
+		}
+		if outOfOrder {
+			direction := "ascending"
+			if chk.descending {
+				direction = "descending"
+			}
+			msg := "Command output was not sorted in " + direction + " order:"
+			msg += "\n\tCommand: " + chk.Command
+			msg += "\n\tLine " + fmt.Sprint(i) + ": " + prev
+			msg += "\n\tLine " + fmt.Sprint(i+1) + ": " + next
+			return 1, msg, nil
+		}
+	}
+	return errutil.Success()
+}
+
+// parseColumn validates a column parameter for commandColumn, which can either
+// be a header or a non-negative index
+func parseColumn(column string) error {
+	if strings.TrimSpace(column) == "" {
+		return errutil.ParameterTypeError{column, "column header or index"}
+	} else if i, err := strconv.Atoi(column); err == nil && i < 0 {
+		return errutil.ParameterTypeError{column, "column header or index"}
+	}
+	return nil
+}
+
+// commandColumn runs the command and returns one column of its output, as split
+// by tabular.ProbabalisticSplit. The column can be specified either by its
+// header (which is then left out), or by its index, counting from zero (which
+// includes every row, for output without headers).
+func commandColumn(command string, column string) (tabular.Column, error) {
+	cmd := exec.Command("bash", "-c", command)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.New(err.Error() + ": output: " + string(out))
+	}
+	table := tabular.ProbabalisticSplit(string(out))
+	if i, err := strconv.Atoi(column); err == nil {
+		return tabular.GetColumn(i, table), nil
+	}
+	return tabular.GetColumnByHeader(column, table), nil
+}
+
+/*
+#### CommandColumnUnique
+Description: Are all the values in this column of the output of this Command
+unique? Alternatively, does the column have exactly this many distinct values?
+Parameters:
+  - Cmd (string): Command to be executed
+  - Column (string or int): Header of the column, or its index (from zero)
+  - Count (positive int): Expected number of distinct values, or "" for all
+    unique
+Example parameters:
+  - "cat /etc/hosts | grep -v '^#'", "ip -4 -o addr"
+  - 0, 3, "IP", "MAC Address"
+  - "", 1, 5
+*/
+
+type CommandColumnUnique struct {
+	Command, column string
+	count           int // -1 if all values should be unique
+}
+
+func (chk CommandColumnUnique) ID() string { return "CommandColumnUnique" }
+
+func (chk CommandColumnUnique) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if err := parseColumn(params[1]); err != nil {
+		return chk, err
+	}
+	chk.count = -1
+	if params[2] != "" {
+		count, err := strconv.ParseUint(params[2], 10, 31)
+		if err != nil || count < 1 {
+			return chk, errutil.ParameterTypeError{params[2], "positive int"}
+		}
+		chk.count = int(count)
+	}
+	chk.Command = params[0]
+	chk.column = params[1]
+	return chk, nil
+}
+
+func (chk CommandColumnUnique) Status() (int, string, error) {
+	column, err := commandColumn(chk.Command, chk.column)
+	if err != nil {
+		return 1, "", err
+	} else if len(column) < 1 {
+		return 1, "Column was empty or not found: " + chk.column, nil
+	}
+	// count the occurences of each value, remembering their order
+	counts := make(map[string]int)
+	var distinct []string
+	for _, value := range column {
+		if counts[value] == 0 {
+			distinct = append(distinct, value)
+		}
+		counts[value]++
+	}
+	if chk.count >= 0 {
+		if len(distinct) == chk.count {
+			return errutil.Success()
+		}
+		msg := "Column " + chk.column + " had " + fmt.Sprint(len(distinct))
+		msg += " distinct values"
+		return errutil.GenericError(msg, chk.count, distinct)
+	}
+	var duplicates []string
+	for _, value := range distinct {
+		if counts[value] > 1 {
+			duplicates = append(duplicates, value)
+		}
+	}
+	if len(duplicates) == 0 {
+		return errutil.Success()
+	}
+	msg := "Column " + chk.column + " had duplicate values:"
+	msg += "\n\tCommand: " + chk.Command
+	for _, value := range duplicates {
+		msg += "\n\t" + value + " (" + fmt.Sprint(counts[value]) + " times)"
+	}
+	return 1, msg, nil
+}
+
+/*
+#### CommandColumnContains
+Description: Does this column of the output of this Command contain this value?
+Parameters:
+  - Cmd (string): Command to be executed
+  - Column (string or int): Header of the column, or its index (from zero)
+  - Value (string): Value to look for in the column
+Example parameters:
+  - "route -n", "ip -4 -o addr", "docker ps"
+  - "Gateway", "Iface", 3, "IMAGE"
+  - "192.168.0.1", "eth0", "10.0.0.5/24", "redis"
+*/
+
+type CommandColumnContains struct{ Command, column, value string }
+
+func (chk CommandColumnContains) ID() string { return "CommandColumnContains" }
+
+func (chk CommandColumnContains) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if err := parseColumn(params[1]); err != nil {
+		return chk, err
+	}
+	chk.Command = params[0]
+	chk.column = params[1]
+	chk.value = params[2]
+	return chk, nil
+}
+
+func (chk CommandColumnContains) Status() (int, string, error) {
+	column, err := commandColumn(chk.Command, chk.column)
+	if err != nil {
+		return 1, "", err
+	} else if tabular.StrIn(chk.value, column) {
+		return errutil.Success()
+	}
+	msg := "Value not found in column " + chk.column + " of command output"
+	return errutil.GenericError(msg, chk.value, column)
+}
+
 /*
 #### Running
 Description: Is a process by this exact name Running (excluding this process)?
@@ -313,6 +998,60 @@ func (chk KernelParameter) Status() (int, string, error) {
 	return 1, "Kernel parameter not set: " + chk.name, nil
 }
 
+// cmdlineHas reports whether a kernel command line has this parameter. A bare
+// key (like "quiet" or "audit") matches with any value, while "key=value"
+// has to match exactly.
+func cmdlineHas(cmdline string, param string) bool {
+	for _, field := range strings.Fields(cmdline) {
+		if field == param {
+			return true
+		} else if !strings.Contains(param, "=") &&
+			strings.SplitN(field, "=", 2)[0] == param {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+#### KernelCmdline
+Description: Was the kernel booted with this command line parameter? Useful
+for boot-time settings that aren't visible via sysctl.
+Parameters:
+  - Parameter (string): Either a key, which may have any value, or key=value
+Example parameters:
+  - "audit=1", "mitigations=off", "quiet", "isolcpus"
+Depedencies:
+  - /proc/cmdline
+*/
+
+type KernelCmdline struct{ param string }
+
+func (chk KernelCmdline) ID() string { return "KernelCmdline" }
+
+func (chk KernelCmdline) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	} else if len(strings.Fields(params[0])) != 1 {
+		return chk, errutil.ParameterTypeError{params[0], "kernel parameter"}
+	}
+	chk.param = params[0]
+	return chk, nil
+}
+
+func (chk KernelCmdline) Status() (int, string, error) {
+	data, err := ioutil.ReadFile("/proc/cmdline")
+	if err != nil {
+		return 1, "", err
+	}
+	cmdline := strings.TrimSpace(string(data))
+	if cmdlineHas(cmdline, chk.param) {
+		return errutil.Success()
+	}
+	msg := "Kernel command line did not have parameter"
+	return errutil.GenericError(msg, chk.param, []string{cmdline})
+}
+
 /*
 #### PHPConfig
 Description: Does this PHP configuration variable have this value?
@@ -364,3 +1103,241 @@ func (chk PHPConfig) Status() (int, string, error) {
 	msg := "PHP variable did not match expected value"
 	return errutil.GenericError(msg, chk.value, []string{actualValue})
 }
+
+/*
+#### Hostname
+Description: Does this host's name match this regexp?
+Parameters:
+  - Regexp (regexp): Regexp that the whole hostname should match
+  - Form (string): short | fqdn | persistent
+Example parameters:
+  - "web01", "web\d{2}", "db01.example.com", ".+\.example\.com"
+  - short, fqdn, persistent
+Depedencies:
+  - `hostname -f` (for fqdn)
+  - /etc/hostname (for persistent)
+*/
+
+type Hostname struct {
+	re   *regexp.Regexp
+	form string
+}
+
+func (chk Hostname) ID() string { return "Hostname" }
+
+func (chk Hostname) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	// the hostname has to match in its entirety
+	re, err := regexp.Compile("^(?:" + params[0] + ")$")
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "regexp"}
+	}
+	form := strings.ToLower(params[1])
+	if !tabular.StrIn(form, []string{"short", "fqdn", "persistent"}) {
+		return chk, errutil.ParameterTypeError{params[1], "short | fqdn | persistent"}
+	}
+	chk.re = re
+	chk.form = form
+	return chk, nil
+}
+
+func (chk Hostname) Status() (int, string, error) {
+	// getHostname returns the hostname of this machine in the requested form
+	getHostname := func(form string) (string, error) {
+		switch form {
+		case "fqdn":
+			cmd := exec.Command("hostname", "-f")
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				return "", errors.New(err.Error() + ": output: " + string(out))
+			}
+			return strings.TrimSpace(string(out)), nil
+		case "persistent":
+			data, err := ioutil.ReadFile("/etc/hostname")
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(data)), nil
+		}
+		name, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		// the short name is everything before the first dot
+		return strings.Split(name, ".")[0], nil
+	}
+	actual, err := getHostname(chk.form)
+	if err != nil {
+		return 1, "", err
+	} else if chk.re.MatchString(actual) {
+		return errutil.Success()
+	}
+	msg := "Hostname did not match (" + chk.form + ")"
+	return errutil.GenericError(msg, chk.re.String(), []string{actual})
+}
+
+// machineIDPath is where systemd keeps this machine's unique ID
+const machineIDPath = "/etc/machine-id"
+
+// validMachineID asks: Is this a well formed machine ID (32 lowercase hex
+// characters)?
+func validMachineID(id string) bool {
+	return regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id)
+}
+
+// machineIDCheck is an abstraction of MachineID and MachineIDNot. It fails if
+// the machine ID is absent or empty, and otherwise reports whether the ID
+// equals expected (or differs from it, if negate is set). An empty expected
+// value only requires the ID to be present.
+func machineIDCheck(expected string, negate bool) (int, string, error) {
+	data, err := ioutil.ReadFile(machineIDPath)
+	if os.IsNotExist(err) {
+		return 1, "No machine ID found at " + machineIDPath, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	actual := strings.TrimSpace(string(data))
+	switch {
+	case actual == "":
+		return 1, "Machine ID was empty: " + machineIDPath, nil
+	case expected == "":
+		return errutil.Success()
+	case !negate && actual == expected:
+		return errutil.Success()
+	case negate && actual != expected:
+		return errutil.Success()
+	case negate:
+		return 1, "Machine ID was forbidden value: " + actual, nil
+	}
+	msg := "Machine ID did not match"
+	return errutil.GenericError(msg, expected, []string{actual})
+}
+
+/*
+#### MachineID
+Description: Is /etc/machine-id present, non-empty, and equal to this value?
+Parameters:
+  - ID (string): Expected machine ID, or "" to just check that one is present
+Example parameters:
+  - "", "fed6b2924c424cf1b9a322f606b4de6d"
+Depedencies:
+  - /etc/machine-id
+*/
+
+type MachineID struct{ expected string }
+
+func (chk MachineID) ID() string { return "MachineID" }
+
+func (chk MachineID) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	} else if params[0] != "" && !validMachineID(params[0]) {
+		return chk, errutil.ParameterTypeError{params[0], "machine ID"}
+	}
+	chk.expected = params[0]
+	return chk, nil
+}
+
+func (chk MachineID) Status() (int, string, error) {
+	return machineIDCheck(chk.expected, false)
+}
+
+/*
+#### MachineIDNot
+Description: Like MachineID, but passes only if the ID is *not* this value.
+Useful for catching cloned VMs that kept their template's machine ID.
+Parameters:
+  - ID (string): Forbidden machine ID
+Example parameters:
+  - "fed6b2924c424cf1b9a322f606b4de6d"
+*/
+
+type MachineIDNot struct{ forbidden string }
+
+func (chk MachineIDNot) ID() string { return "MachineIDNot" }
+
+func (chk MachineIDNot) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	} else if !validMachineID(params[0]) {
+		return chk, errutil.ParameterTypeError{params[0], "machine ID"}
+	}
+	chk.forbidden = params[0]
+	return chk, nil
+}
+
+func (chk MachineIDNot) Status() (int, string, error) {
+	return machineIDCheck(chk.forbidden, true)
+}
+
+// parseBootTime finds the boot time in the contents of /proc/stat, which
+// records it in its btime line as seconds since the epoch
+func parseBootTime(stat string) (time.Time, error) {
+	for _, line := range strings.Split(stat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			seconds, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("No btime line found in /proc/stat")
+}
+
+/*
+#### BootTime
+Description: Does the time since this machine booted satisfy this comparison?
+Parameters:
+  - Operator (string): < | <= | == | != | >= | >
+  - Age (time.Duration): Duration to compare the time since boot against
+Example parameters:
+  - ">", "<"
+  - 10m, 24h, 720h
+Depedencies:
+  - /proc/stat
+*/
+
+type BootTime struct {
+	operator string
+	age      time.Duration
+}
+
+func (chk BootTime) ID() string { return "BootTime" }
+
+func (chk BootTime) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if !chkutil.ValidOperator(params[0]) {
+		return chk, errutil.ParameterTypeError{params[0], "operator"}
+	}
+	age, err := time.ParseDuration(params[1])
+	if err != nil || age <= 0 {
+		return chk, errutil.ParameterTypeError{params[1], "time.Duration"}
+	}
+	chk.operator = params[0]
+	chk.age = age
+	return chk, nil
+}
+
+func (chk BootTime) Status() (int, string, error) {
+	data, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return 1, "", err
+	}
+	bootTime, err := parseBootTime(string(data))
+	if err != nil {
+		return 1, "", err
+	}
+	age := time.Since(bootTime)
+	if chkutil.Compare(chk.operator, age.Seconds(), chk.age.Seconds()) {
+		return errutil.Success()
+	}
+	msg := "Machine booted at " + bootTime.Format(time.RFC3339)
+	msg += ", " + age.String() + " ago"
+	expected := chk.operator + " " + chk.age.String()
+	return errutil.GenericError(msg, expected, []string{age.String()})
+}
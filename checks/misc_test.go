@@ -1,22 +1,64 @@
 package checks
 
-import "testing"
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestCommand(t *testing.T) {
 	t.Parallel()
 	validInputs := [][]string{
 		{"sleep 0.00000001"}, {"echo this works"}, {"cd"}, {"mv --help"},
+		{"FOO=bar env"}, {"/bin/true"},
 	}
-	invalidInputs := notLengthOne
+	// executables are looked up in the $PATH as soon as the check is created
+	invalidInputs := append(notLengthOne, names...)
+	invalidInputs = append(invalidInputs, []string{"/steppenwolf --help"})
 	goodEggs := validInputs
 	badEggs := [][]string{
 		{"sleep fail"}, {"cd /steppenwolf"}, {"mv /glass /bead-game"},
+		{"$NOT_SET_ANYWHERE --help"},
 	}
-	badEggs = append(badEggs, names...)
 	testParameters(validInputs, invalidInputs, Command{}, t)
 	testCheck(goodEggs, badEggs, Command{}, t)
 }
 
+func TestCommandExecutable(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"mv --help": "mv", "  /bin/true": "/bin/true", "cd /tmp": "",
+		"FOO=bar env": "", "$SHELL -c true": "", "(ls)": "", "": "",
+	}
+	for command, expected := range tests {
+		if actual := commandExecutable(command); actual != expected {
+			t.Errorf("Expected %q for command %q, got %q", expected, command, actual)
+		}
+	}
+}
+
+func TestExecutableInPath(t *testing.T) {
+	t.Parallel()
+	validInputs := append(names, []string{"bash"})
+	invalidInputs := append(notLengthOne, []string{""})
+	testParameters(validInputs, invalidInputs, ExecutableInPath{}, t)
+	testCheck([][]string{}, names, ExecutableInPath{}, t)
+	// success reports where the executable was found
+	for _, name := range []string{"bash", "mv", "/bin/sh"} {
+		chk, err := ExecutableInPath{}.New([]string{name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, msg, err := chk.Status()
+		if code != 0 || err != nil || !strings.Contains(msg, "/") {
+			t.Errorf("Expected to find %s, got (%d, %q, %v)", name, code, msg, err)
+		}
+	}
+}
+
 func TestCommandOutputMatches(t *testing.T) {
 	t.Parallel()
 	validInputs := [][]string{
@@ -33,6 +75,211 @@ func TestCommandOutputMatches(t *testing.T) {
 	testCheck(goodEggs, badEggs, CommandOutputMatches{}, t)
 }
 
+func TestCommandOutputMatchCount(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"seq 10", "1", "==", "2"}, {"echo aaaa", "aa", "<=", "0"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"seq 10", "(1", "==", "2"}, {"seq 10", "1", "=", "2"},
+		{"seq 10", "1", "==", "-2"}, {"seq 10", "1", "==", "two"},
+	}...)
+	goodEggs := [][]string{
+		{"seq 10", "1", "==", "2"}, {"echo aaaa", "aa", "==", "2"},
+		{"seq 10", `(?m)^\d$`, ">=", "9"}, {"echo haskell", "curry", "==", "0"},
+	}
+	badEggs := [][]string{
+		{"seq 10", "1", "<", "2"}, {"echo aaaa", "a", "!=", "4"},
+		{"echo haskell", "curry", ">", "0"},
+	}
+	testParameters(validInputs, invalidInputs, CommandOutputMatchCount{}, t)
+	testCheck(goodEggs, badEggs, CommandOutputMatchCount{}, t)
+}
+
+func TestCommandOutputsCompare(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"echo 1", "echo 2", "<", "0"}, {"nproc", "echo 1", ">=", "0.5"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"echo 1", "echo 2", "=", "0"}, {"echo 1", "echo 2", "<", "-1"},
+		{"echo 1", "echo 2", "<", "none"},
+	}...)
+	goodEggs := [][]string{
+		{"echo 1", "echo 2", "<", "0"}, {"echo 1.4", "echo 1", "==", "0.5"},
+		{"nproc", "echo 0", ">", "0"}, {"echo 10", "echo ' 9 '", ">=", "2"},
+	}
+	badEggs := [][]string{
+		{"echo 1", "echo 2", ">", "0"}, {"echo 1.4", "echo 1", ">", "0.5"},
+		{"echo one", "echo 2", "<", "0"}, {"echo 1", "exit 1", "<", "0"},
+	}
+	testParameters(validInputs, invalidInputs, CommandOutputsCompare{}, t)
+	testCheck(goodEggs, badEggs, CommandOutputsCompare{}, t)
+}
+
+func TestLineDiff(t *testing.T) {
+	t.Parallel()
+	old := []string{"a", "b", "c", "d"}
+	new := []string{"a", "c", "d", "e"}
+	if diff, complete := lineDiff(old, new); fmt.Sprint(diff) != "[-b +e]" || !complete {
+		t.Errorf("Unexpected diff: %v", diff)
+	}
+	if diff, _ := lineDiff(old, old); len(diff) != 0 {
+		t.Errorf("Expected no diff for identical lines, got %v", diff)
+	}
+	if diff, _ := lineDiff(nil, []string{"x"}); fmt.Sprint(diff) != "[+x]" {
+		t.Errorf("Unexpected diff: %v", diff)
+	}
+	// too large to diff in full, even without the common first and last lines
+	var big1, big2 []string
+	for i := 0; i < 2000; i++ {
+		big1 = append(big1, fmt.Sprint("line ", i))
+		big2 = append(big2, fmt.Sprint("line ", i*7))
+	}
+	if diff, complete := lineDiff(big1, big2); complete ||
+		fmt.Sprint(diff) != "[-line 1 +line 7]" {
+		t.Errorf("Unexpected diff of large inputs: %v, %v", diff, complete)
+	}
+	// but fine if most lines are shared
+	big2 = append(append([]string{}, big1...), "extra")
+	if diff, complete := lineDiff(big1, big2); !complete || fmt.Sprint(diff) != "[+extra]" {
+		t.Errorf("Unexpected diff of similar large inputs: %v, %v", diff, complete)
+	}
+}
+
+func TestCommandMatchesGolden(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "seq")
+	data := "# Generated on Thursday\n1  \n2\n3\n\n"
+	if err := ioutil.WriteFile(golden, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	validInputs := [][]string{{"seq 3", golden, ""}, {"seq 3", "/golden", "^#"}}
+	invalidInputs := [][]string{
+		{}, {"seq 3"}, {"seq 3", golden}, {"seq 3", golden, "", ""},
+		{"seq 3", golden, "(#"},
+	}
+	goodEggs := [][]string{
+		{"seq 3", golden, "^#"}, {"echo '# Generated on Friday'; seq 3", golden, "^#"},
+		{"printf '1\\n2\\n3   \\n\\n\\n'", golden, "^# Generated"},
+	}
+	badEggs := [][]string{
+		{"seq 3", golden, ""}, {"seq 4", golden, "^#"}, {"seq 3", "/steppenwolf", ""},
+		// matching output, but a failing command
+		{"seq 3; false", golden, "^#"}, {"seq 3; exit 2", golden, "^#"},
+	}
+	testParameters(validInputs, invalidInputs, CommandMatchesGolden{}, t)
+	testCheck(goodEggs, badEggs, CommandMatchesGolden{}, t)
+}
+
+func TestCommandStable(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"echo this works", "1"}, {"cd", "10"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"cd", "0"}, {"cd", "-1"}, {"cd", "many"}, {"cd", ""},
+	}...)
+	goodEggs := [][]string{{"echo this works", "3"}, {"mv --help", "2"}}
+	badEggs := [][]string{
+		{"cd /steppenwolf", "2"},
+		// fails on every other run
+		{"[ -e /tmp/distributive-flaky ] && rm /tmp/distributive-flaky || " +
+			"{ touch /tmp/distributive-flaky; false; }", "4"},
+	}
+	testParameters(validInputs, invalidInputs, CommandStable{}, t)
+	testCheck(goodEggs, badEggs, CommandStable{}, t)
+}
+
+func TestCommandOutputSorted(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"echo a", "asc"}, {"ls /", "DESC"}, {"echo a", "version-asc"},
+		{"echo a", "Version-Desc"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"echo a", "up"}, {"echo a", ""}, {"echo a", "version-"},
+		{"echo a", "version"},
+	}...)
+	versions := `printf '1.2\n1.9\n1.10\n1.10.1\n2.0-rc1\n'`
+	goodEggs := [][]string{
+		{`printf 'a\nb\nb\nc\n'`, "asc"}, {`printf '3\n2\n1'`, "desc"},
+		{"echo single", "asc"}, {"echo single", "desc"}, {"true", "asc"},
+		{versions, "version-asc"}, {versions + " | tac", "version-desc"},
+		{`printf 'v01\nv2\nv10'`, "version-asc"},
+	}
+	badEggs := [][]string{
+		{`printf 'a\nc\nb\n'`, "asc"}, {`printf '1\n2\n3'`, "desc"},
+		{`printf 'a\nb'`, "desc"}, {versions, "asc"},
+		{versions, "version-desc"}, {`printf '1.10\n1.9'`, "version-asc"},
+	}
+	testParameters(validInputs, invalidInputs, CommandOutputSorted{}, t)
+	testCheck(goodEggs, badEggs, CommandOutputSorted{}, t)
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+	inputs := [][2]string{
+		{"1.9", "1.10"}, {"1.10", "1.9"}, {"1.10", "1.10"}, {"1.10", "1.10.1"},
+		{"v2", "v010"}, {"a", "b"}, {"", "1"}, {"99999999999999999999", "100000000000000000000"},
+		{"1.0-rc1", "1.0-rc2"},
+	}
+	outputs := []int{-1, 1, 0, -1, -1, -1, -1, -1, -1}
+	for i, input := range inputs {
+		if actual := compareVersions(input[0], input[1]); actual != outputs[i] {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d",
+				input[0], input[1], actual, outputs[i])
+		}
+	}
+}
+
+func TestCommandColumnUnique(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"echo a", "0", ""}, {"ps aux", "PID", ""}, {"echo a", "1", "3"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"echo a", "", ""}, {"echo a", "-1", ""}, {"echo a", "0", "-1"},
+		{"echo a", "0", "0"}, {"echo a", "0", "many"},
+	}...)
+	table := `printf 'NAME  IP\nfoo  10.0.0.1\nbar  10.0.0.2\nbaz  10.0.0.1\n'`
+	goodEggs := [][]string{
+		{table, "NAME", ""}, {table, "name", "3"}, {table, "IP", "2"},
+		{table, "0", ""}, {"seq 10", "0", "10"},
+	}
+	badEggs := [][]string{
+		{table, "IP", ""}, {table, "IP", "3"}, {table, "NAME", "1"},
+		{table, "1", ""}, {table, "MAC", ""}, {"seq 10", "0", "9"},
+	}
+	testParameters(validInputs, invalidInputs, CommandColumnUnique{}, t)
+	testCheck(goodEggs, badEggs, CommandColumnUnique{}, t)
+}
+
+func TestCommandColumnContains(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"echo a", "0", "a"}, {"route -n", "Gateway", "0.0.0.0"},
+		{"echo a", "Iface", ""},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"echo a", "", "a"}, {"echo a", "-2", "a"},
+	}...)
+	table := `printf 'NAME  IP\nfoo  10.0.0.1\nbar  10.0.0.2\n'`
+	goodEggs := [][]string{
+		{table, "NAME", "foo"}, {table, "ip", "10.0.0.2"}, {table, "1", "IP"},
+		{"seq 10", "0", "7"},
+	}
+	badEggs := [][]string{
+		{table, "NAME", "NAME"}, {table, "IP", "foo"}, {table, "MAC", "foo"},
+		{table, "2", "foo"}, {"seq 10", "0", "11"},
+	}
+	testParameters(validInputs, invalidInputs, CommandColumnContains{}, t)
+	testCheck(goodEggs, badEggs, CommandColumnContains{}, t)
+}
+
 func TestRunning(t *testing.T) {
 	t.Parallel()
 	validInputs := append(names, [][]string{
@@ -82,6 +329,39 @@ func TestKernelParameter(t *testing.T) {
 	testCheck(goodEggs, badEggs, KernelParameter{}, t)
 }
 
+func TestCmdlineHas(t *testing.T) {
+	t.Parallel()
+	cmdline := "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet audit=1"
+	inputs := []string{
+		"quiet", "audit", "audit=1", "root=/dev/sda1", "ro", "audit=0",
+		"rw", "BOOT", "quiet=1",
+	}
+	outputs := []bool{true, true, true, true, true, false, false, false, false}
+	for i := range inputs {
+		if actual := cmdlineHas(cmdline, inputs[i]); actual != outputs[i] {
+			msg := "cmdlineHas gave unexpected result"
+			msg += "\n\tParameter: " + inputs[i]
+			msg += "\n\tExpected: " + fmt.Sprint(outputs[i])
+			t.Error(msg)
+		}
+	}
+}
+
+func TestKernelCmdline(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"audit=1"}, {"quiet"}, {"mitigations=off"}}
+	invalidInputs := append(notLengthOne, [][]string{{""}, {"quiet ro"}}...)
+	goodEggs := [][]string{}
+	if data, err := ioutil.ReadFile("/proc/cmdline"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			goodEggs = append(goodEggs, []string{fields[0]})
+		}
+	}
+	badEggs := [][]string{{"lkjashldfb"}, {"lkjashldfb=1"}}
+	testParameters(validInputs, invalidInputs, KernelCmdline{}, t)
+	testCheck(goodEggs, badEggs, KernelCmdline{}, t)
+}
+
 func TestPHPConfig(t *testing.T) {
 	t.Parallel()
 	validInputs := appendParameter(names, "dummy-value")
@@ -91,3 +371,64 @@ func TestPHPConfig(t *testing.T) {
 	testParameters(validInputs, invalidInputs, PHPConfig{}, t)
 	testCheck(goodEggs, badEggs, PHPConfig{}, t)
 }
+
+func TestHostname(t *testing.T) {
+	t.Parallel()
+	validInputs := append(appendParameter(names, "short"),
+		appendParameter(names, "fqdn")...)
+	invalidInputs := append(notLengthTwo,
+		[][]string{{"[", "short"}, {"web01", "medium"}}...)
+	goodEggs := [][]string{{".+", "short"}, {`[^\.]+`, "short"}}
+	badEggs := appendParameter(names, "short")
+	testParameters(validInputs, invalidInputs, Hostname{}, t)
+	testCheck(goodEggs, badEggs, Hostname{}, t)
+}
+
+var machineIDs = [][]string{
+	{"00000000000000000000000000000000"}, {"0123456789abcdef0123456789abcdef"},
+}
+
+func TestMachineID(t *testing.T) {
+	t.Parallel()
+	validInputs := append(machineIDs, []string{""})
+	invalidInputs := append(append(notLengthOne, names...),
+		[]string{"0123456789ABCDEF0123456789ABCDEF"})
+	goodEggs := [][]string{{""}}
+	badEggs := machineIDs
+	testParameters(validInputs, invalidInputs, MachineID{}, t)
+	testCheck(goodEggs, badEggs, MachineID{}, t)
+}
+
+func TestMachineIDNot(t *testing.T) {
+	t.Parallel()
+	invalidInputs := append(append(notLengthOne, names...), []string{""})
+	testParameters(machineIDs, invalidInputs, MachineIDNot{}, t)
+	testCheck(machineIDs, [][]string{}, MachineIDNot{}, t)
+}
+
+func TestParseBootTime(t *testing.T) {
+	t.Parallel()
+	stat := "cpu  2255 34 2290 22625563 6290 127 456 0 0 0\nintr 114930548\n" +
+		"ctxt 1990473\nbtime 1452168000\nprocesses 2915\n"
+	bootTime, err := parseBootTime(stat)
+	if err != nil {
+		t.Error(err)
+	} else if bootTime.Unix() != 1452168000 {
+		t.Errorf("Expected boot time 1452168000, got %v", bootTime.Unix())
+	}
+	if _, err := parseBootTime("cpu  2255 34 2290\n"); err == nil {
+		t.Error("Expected an error for /proc/stat without btime")
+	}
+}
+
+func TestBootTime(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{">", "10m"}, {"<=", "720h"}, {"!=", "1s"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"=", "10m"}, {">", "10"}, {">", "-10m"}, {">", "0s"},
+	}...)
+	goodEggs := [][]string{{">", "1ns"}, {"<", "876000h"}}
+	badEggs := [][]string{{"<", "1ns"}, {">", "876000h"}}
+	testParameters(validInputs, invalidInputs, BootTime{}, t)
+	testCheck(goodEggs, badEggs, BootTime{}, t)
+}
@@ -1,16 +1,26 @@
 package checks
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/netstatus"
 	"github.com/zeldal/distributive/tabular"
-	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"math"
 	"net"
+	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -142,6 +152,144 @@ func (chk PortUDP) Status() (int, string, error) {
 	return errutil.GenericError("Port not open", fmt.Sprint(chk.port), strPorts)
 }
 
+/*
+#### ProcessListensOn
+Description: Is a process by this name listening on all of these TCP ports?
+Parameters:
+  - Name (string): Process name, as in /proc/[pid]/comm
+  - Ports (comma-separated list of uint16): Ports the process should own
+Example parameters:
+  - "nginx", "sshd", "haproxy"
+  - "80,443", "22", "8080"
+Dependencies:
+  - /proc/net/tcp
+  - /proc/net/tcp6
+  - /proc/[pid]/fd (usually requires root for other users' processes)
+*/
+
+type ProcessListensOn struct {
+	name  string
+	ports []uint16
+}
+
+func (chk ProcessListensOn) ID() string { return "ProcessListensOn" }
+
+func (chk ProcessListensOn) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	for _, portStr := range strings.Split(params[1], ",") {
+		port, err := parsePort(strings.TrimSpace(portStr))
+		if err != nil {
+			return chk, errutil.ParameterTypeError{params[1], "[]uint16"}
+		}
+		chk.ports = append(chk.ports, port)
+	}
+	chk.name = params[0]
+	return chk, nil
+}
+
+func (chk ProcessListensOn) Status() (int, string, error) {
+	ports, pids, err := netstatus.ProcessListeningPorts(chk.name)
+	if err != nil {
+		return 1, "", err
+	} else if len(pids) < 1 {
+		return 1, "Process not running: " + chk.name, nil
+	}
+	var strPorts []string
+	for _, port := range ports {
+		strPorts = append(strPorts, fmt.Sprint(port))
+	}
+	for _, port := range chk.ports {
+		if !tabular.StrIn(fmt.Sprint(port), strPorts) {
+			msg := "Process not listening on port " + fmt.Sprint(port)
+			return errutil.GenericError(msg, chk.name, strPorts)
+		}
+	}
+	return errutil.Success()
+}
+
+/*
+#### CloseWaitSockets
+Description: Are there at most this many TCP sockets in the CLOSE_WAIT state?
+A growing number of these usually means that an application isn't closing its
+connections.
+Parameters:
+  - Max (int): Maximum number of sockets in CLOSE_WAIT
+  - Port (uint16): Only count sockets on this local port, or "" for all ports
+Example parameters:
+  - 0, 10, 100
+  - "", 80, 8080
+Dependencies:
+  - /proc/net/tcp
+  - /proc/net/tcp6
+*/
+
+type CloseWaitSockets struct {
+	max    int
+	port   uint16
+	filter bool
+}
+
+func (chk CloseWaitSockets) ID() string { return "CloseWaitSockets" }
+
+func (chk CloseWaitSockets) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	max, err := strconv.ParseUint(params[0], 10, 31)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "positive int"}
+	}
+	if params[1] != "" {
+		port, err := parsePort(params[1])
+		if err != nil {
+			return chk, errutil.ParameterTypeError{params[1], "uint16"}
+		}
+		chk.port = port
+		chk.filter = true
+	}
+	chk.max = int(max)
+	return chk, nil
+}
+
+func (chk CloseWaitSockets) Status() (int, string, error) {
+	sockets, err := netstatus.TCPSockets()
+	if err != nil {
+		return 1, "", err
+	}
+	count := 0
+	for _, socket := range sockets {
+		if socket.State != netstatus.TCPCloseWait {
+			continue
+		} else if !chk.filter || socket.LocalPort == chk.port {
+			count++
+		}
+	}
+	if count <= chk.max {
+		return errutil.Success()
+	}
+	msg := "Too many sockets in CLOSE_WAIT"
+	if chk.filter {
+		msg += " on port " + fmt.Sprint(chk.port)
+	}
+	return errutil.GenericError(msg, chk.max, []string{fmt.Sprint(count)})
+}
+
+// interfaceNames returns the names of all network interfaces
+func interfaceNames() (interfaces []string) {
+	for _, iface := range netstatus.GetInterfaces() {
+		interfaces = append(interfaces, iface.Name)
+	}
+	return interfaces
+}
+
+// interfaceNotFound returns a (int, string, error) for checks on interfaces
+// that don't exist
+func interfaceNotFound(name string) (int, string, error) {
+	return errutil.GenericError("Interface does not exist", name, interfaceNames())
+}
+
 /*
 #### InterfaceExists
 Description: Does this interface exist?
@@ -164,20 +312,10 @@ func (chk InterfaceExists) New(params []string) (chkutil.Check, error) {
 }
 
 func (chk InterfaceExists) Status() (int, string, error) {
-	// getInterfaceNames returns the names of all network interfaces
-	getInterfaceNames := func() (interfaces []string) {
-		for _, iface := range netstatus.GetInterfaces() {
-			interfaces = append(interfaces, iface.Name)
-		}
-		return
-	}
-	interfaces := getInterfaceNames()
-	for _, iface := range interfaces {
-		if iface == chk.name {
-			return errutil.Success()
-		}
+	if tabular.StrIn(chk.name, interfaceNames()) {
+		return errutil.Success()
 	}
-	return errutil.GenericError("Interface does not exist", chk.name, interfaces)
+	return interfaceNotFound(chk.name)
 }
 
 /*
@@ -219,6 +357,219 @@ func (chk Up) Status() (int, string, error) {
 	return errutil.GenericError("Interface is not up", chk.name, upInterfaces)
 }
 
+/*
+#### InterfaceThroughput
+Description: Is this interface receiving or transmitting at most this many
+bytes per second, as measured over this interval?
+Parameters:
+  - Name (string): name of the interface
+  - Direction (string): rx | tx
+  - Max (amount): Maximum throughput per second
+  - Interval (time.Duration): How long to measure throughput for
+Example parameters:
+  - lo, wlp1s0, docker0
+  - rx, tx
+  - 10mb, 125000000b, 1gb
+  - 1s, 5s
+Dependencies:
+  - /sys/class/net/<interface>/statistics
+*/
+
+type InterfaceThroughput struct {
+	name, direction string
+	maxBytes        uint64
+	interval        time.Duration
+}
+
+func (chk InterfaceThroughput) ID() string { return "InterfaceThroughput" }
+
+func (chk InterfaceThroughput) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	}
+	direction := strings.ToLower(params[1])
+	if direction != "rx" && direction != "tx" {
+		return chk, errutil.ParameterTypeError{params[1], "rx | tx"}
+	}
+	maxBytes, err := chkutil.ParseBytes(params[2])
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[2], "amount"}
+	}
+	interval, err := time.ParseDuration(params[3])
+	if err != nil || interval <= 0 {
+		return chk, errutil.ParameterTypeError{params[3], "time.Duration"}
+	}
+	chk.name = params[0]
+	chk.direction = direction
+	chk.maxBytes = maxBytes
+	chk.interval = interval
+	return chk, nil
+}
+
+func (chk InterfaceThroughput) Status() (int, string, error) {
+	before, err := netstatus.InterfaceBytes(chk.name, chk.direction)
+	if os.IsNotExist(err) {
+		return interfaceNotFound(chk.name)
+	} else if err != nil {
+		return 1, "", err
+	}
+	time.Sleep(chk.interval)
+	after, err := netstatus.InterfaceBytes(chk.name, chk.direction)
+	if err != nil {
+		return 1, "", err
+	}
+	var rate uint64
+	if after > before { // counters can wrap or be reset
+		rate = uint64(float64(after-before) / chk.interval.Seconds())
+	}
+	if rate <= chk.maxBytes {
+		return errutil.Success()
+	}
+	msg := "Interface " + chk.direction + " throughput was too high (bytes/s)"
+	return errutil.GenericError(msg, chk.maxBytes, []string{fmt.Sprint(rate)})
+}
+
+/*
+#### InterfaceDuplex
+Description: Is this interface running in this duplex mode?
+Parameters:
+  - Name (string): name of the interface
+  - Mode (string): full | half
+Example parameters:
+  - eth0, enp0s3
+  - full, half
+Dependencies:
+  - /sys/class/net/<interface>/duplex
+*/
+
+type InterfaceDuplex struct{ name, mode string }
+
+func (chk InterfaceDuplex) ID() string { return "InterfaceDuplex" }
+
+func (chk InterfaceDuplex) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	mode := strings.ToLower(params[1])
+	if mode != "full" && mode != "half" {
+		return chk, errutil.ParameterTypeError{params[1], "full | half"}
+	}
+	chk.name = params[0]
+	chk.mode = mode
+	return chk, nil
+}
+
+func (chk InterfaceDuplex) Status() (int, string, error) {
+	duplex, err := netstatus.InterfaceDuplex(chk.name)
+	if os.IsNotExist(err) {
+		return interfaceNotFound(chk.name)
+	} else if err != nil {
+		return 1, "", err
+	} else if duplex == "unknown" {
+		msg := "Duplex of interface " + chk.name + " is unknown (is it virtual, "
+		return 1, msg + "or without a carrier?)", nil
+	} else if duplex == chk.mode {
+		return errutil.Success()
+	}
+	msg := "Interface " + chk.name + " had an unexpected duplex"
+	return errutil.GenericError(msg, chk.mode, []string{duplex})
+}
+
+/*
+#### DHCPLease
+Description: Does this interface have a DHCP lease that hasn't expired yet?
+Parameters:
+  - Name (string): name of the interface
+Example parameters:
+  - eth0, wlp1s0, enp0s3
+Dependencies:
+  - dhclient or systemd-networkd lease files
+*/
+
+type DHCPLease struct{ name string }
+
+func (chk DHCPLease) ID() string { return "DHCPLease" }
+
+func (chk DHCPLease) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	}
+	chk.name = params[0]
+	return chk, nil
+}
+
+func (chk DHCPLease) Status() (int, string, error) {
+	if !tabular.StrIn(chk.name, interfaceNames()) {
+		return interfaceNotFound(chk.name)
+	}
+	leases, err := netstatus.DHCPLeases(chk.name)
+	if err != nil {
+		return 1, "", err
+	} else if len(leases) < 1 {
+		msg := "Interface has no DHCP lease (is it statically configured?)"
+		return 1, msg + ": " + chk.name, nil
+	}
+	now := time.Now()
+	var expiries []string
+	for _, lease := range leases {
+		if !lease.Expired(now) {
+			return errutil.Success()
+		}
+		expiries = append(expiries, lease.Expiry.Format(time.RFC3339))
+	}
+	msg := "DHCP lease for interface " + chk.name + " has expired"
+	return errutil.GenericError(msg, "unexpired lease", expiries)
+}
+
+/*
+#### VLANInterface
+Description: Is this interface a VLAN interface with this VLAN id?
+Parameters:
+  - Name (string): name of the interface
+  - ID (int): expected VLAN id, from 0 to 4095
+Example parameters:
+  - eth0.100, bond0.20, vlan10
+  - 100, 20, 10
+Dependencies:
+  - /proc/net/vlan or ip
+*/
+
+type VLANInterface struct {
+	name string
+	id   uint16
+}
+
+func (chk VLANInterface) ID() string { return "VLANInterface" }
+
+func (chk VLANInterface) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	id, err := strconv.ParseUint(params[1], 10, 16)
+	if err != nil || id > 4095 {
+		return chk, errutil.ParameterTypeError{params[1], "VLAN id"}
+	}
+	chk.name = params[0]
+	chk.id = uint16(id)
+	return chk, nil
+}
+
+func (chk VLANInterface) Status() (int, string, error) {
+	if !tabular.StrIn(chk.name, interfaceNames()) {
+		return interfaceNotFound(chk.name)
+	}
+	id, isVLAN, err := netstatus.VLANID(chk.name)
+	if err != nil {
+		return 1, "", err
+	} else if !isVLAN {
+		return 1, "Interface is not a VLAN interface: " + chk.name, nil
+	} else if id == chk.id {
+		return errutil.Success()
+	}
+	msg := "Interface " + chk.name + " had an unexpected VLAN id"
+	return errutil.GenericError(msg, chk.id, []string{fmt.Sprint(id)})
+}
+
 // ipCheck(int, string, error) is an abstraction of IP4 and
 // IP6
 func ipCheck(name string, address *net.IP, version int) (int, string, error) {
@@ -298,6 +649,57 @@ func (chk IP6) Status() (int, string, error) {
 	return ipCheck(chk.name, &chk.ip, 6)
 }
 
+/*
+#### LoopbackAliases
+Description: Does the loopback interface (lo) have all of these addresses?
+Parameters:
+  - Addresses (string): comma-separated list of IP addresses
+Example parameters:
+  - "127.0.0.1", "127.0.0.2,127.0.1.1", "127.0.0.1,::1"
+*/
+
+type LoopbackAliases struct{ ips []net.IP }
+
+func (chk LoopbackAliases) ID() string { return "LoopbackAliases" }
+
+func (chk LoopbackAliases) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	}
+	chk.ips = nil
+	for _, ipStr := range strings.Split(params[0], ",") {
+		ip := net.ParseIP(strings.TrimSpace(ipStr))
+		if ip == nil {
+			return chk, errutil.ParameterTypeError{params[0], "IP list"}
+		}
+		chk.ips = append(chk.ips, ip)
+	}
+	return chk, nil
+}
+
+func (chk LoopbackAliases) Status() (int, string, error) {
+	actual := netstatus.InterfaceIPs("lo")
+	var missing []string
+	for _, expected := range chk.ips {
+		found := false
+		for _, ip := range actual {
+			if ip.Equal(expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, expected.String())
+		}
+	}
+	if len(missing) < 1 {
+		return errutil.Success()
+	}
+	msg := "Loopback interface was missing addresses: "
+	msg += strings.Join(missing, ", ")
+	return errutil.GenericError(msg, chk.ips, actual)
+}
+
 /*
 #### Gateway
 Description: Does the default Gateway have this IP?
@@ -323,17 +725,19 @@ func (chk Gateway) New(params []string) (chkutil.Check, error) {
 
 func (chk Gateway) Status() (int, string, error) {
 	// getGatewayAddress filters all Gateway IPs for a non-zero value
-	getGatewayAddress := func() (addr string) {
-		ips := RoutingTableColumn("Gateway")
+	getGatewayAddress := func() (addr string, err error) {
+		ips, err := RoutingTableColumn("Gateway")
 		for _, ip := range ips {
 			if ip != "0.0.0.0" {
-				return ip
+				return ip, nil
 			}
 		}
-		return "0.0.0.0"
+		return "0.0.0.0", err
 	}
-	GatewayIP := getGatewayAddress()
-	if chk.ip.String() == GatewayIP {
+	GatewayIP, err := getGatewayAddress()
+	if err != nil {
+		return 1, "", err
+	} else if chk.ip.String() == GatewayIP {
 		return errutil.Success()
 	}
 	msg := "Gateway does not have address"
@@ -364,20 +768,28 @@ func (chk GatewayInterface) New(params []string) (chkutil.Check, error) {
 func (chk GatewayInterface) Status() (int, string, error) {
 	// getGatewayInterface returns the interface that the default Gateway is
 	// operating on
-	getGatewayInterface := func() (iface string) {
-		ips := RoutingTableColumn("Gateway")
-		names := RoutingTableColumn("Iface")
+	getGatewayInterface := func() (iface string, err error) {
+		ips, err := RoutingTableColumn("Gateway")
+		if err != nil {
+			return "", err
+		}
+		names, err := RoutingTableColumn("Iface")
+		if err != nil {
+			return "", err
+		}
 		for i, ip := range ips {
 			if ip != "0.0.0.0" {
 				msg := "Fewer names in kernel routing table than IPs"
 				errutil.IndexError(msg, i, names)
-				return names[i] // interface name
+				return names[i], nil // interface name
 			}
 		}
-		return ""
+		return "", nil
 	}
-	iface := getGatewayInterface()
-	if chk.name == iface {
+	iface, err := getGatewayInterface()
+	if err != nil {
+		return 1, "", err
+	} else if chk.name == iface {
 		return errutil.Success()
 	}
 	msg := "Default Gateway does not operate on interface"
@@ -410,6 +822,97 @@ func (chk Host) Status() (int, string, error) {
 	return 1, "Host cannot be resolved: " + chk.hostname, nil
 }
 
+// resolvConfPath is where the system resolver's configuration lives
+var resolvConfPath = "/etc/resolv.conf"
+
+// parseResolvConf returns the search domains configured in resolv.conf(5), and
+// whether it points at systemd-resolved's local stub resolver. The last
+// "search" or "domain" line wins, as it does for the resolver.
+func parseResolvConf(data string) (domains []string, stub bool) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "search", "domain":
+			domains = fields[1:]
+		case "nameserver":
+			stub = stub || fields[1] == "127.0.0.53"
+		}
+	}
+	return domains, stub
+}
+
+// parseResolvectlDomains returns the search domains from the output of
+// `resolvectl domain`, skipping routing-only domains (those prefixed by ~)
+func parseResolvectlDomains(out string) (domains []string) {
+	for _, line := range strings.Split(out, "\n") {
+		// lines look like "Link 2 (eth0): corp.example.com ~."
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		for _, domain := range strings.Fields(line[colon+1:]) {
+			if !strings.HasPrefix(domain, "~") {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains
+}
+
+/*
+#### DNSSearchDomain
+Description: Is this domain one of the resolver's search domains? When
+/etc/resolv.conf points to systemd-resolved's stub resolver, the domains it
+has configured per link are consulted too.
+Parameters:
+  - Domain (string): Expected search domain
+Example parameters:
+  - "example.com", "corp.example.com"
+Dependencies:
+  - /etc/resolv.conf
+  - `resolvectl` (with systemd-resolved)
+*/
+
+type DNSSearchDomain struct{ domain string }
+
+func (chk DNSSearchDomain) ID() string { return "DNSSearchDomain" }
+
+func (chk DNSSearchDomain) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	} else if params[0] == "" || strings.ContainsAny(params[0], " \t,") {
+		return chk, errutil.ParameterTypeError{params[0], "domain"}
+	}
+	chk.domain = strings.ToLower(strings.TrimSuffix(params[0], "."))
+	return chk, nil
+}
+
+func (chk DNSSearchDomain) Status() (int, string, error) {
+	data, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil && !os.IsNotExist(err) {
+		return 1, "", err
+	}
+	domains, stub := parseResolvConf(string(data))
+	if target, err := os.Readlink(resolvConfPath); err == nil {
+		stub = stub || strings.HasSuffix(target, "stub-resolv.conf")
+	}
+	if stub {
+		out, err := exec.Command("resolvectl", "domain").Output()
+		if err == nil {
+			domains = append(domains, parseResolvectlDomains(string(out))...)
+		}
+	}
+	for _, domain := range domains {
+		if strings.ToLower(strings.TrimSuffix(domain, ".")) == chk.domain {
+			return errutil.Success()
+		}
+	}
+	return errutil.GenericError("Search domain not configured", chk.domain, domains)
+}
+
 // TODO improve/fix
 // getConnection(int, string, error) is an abstraction of TCP and UDP
 func connectionCheck(host string, protocol string, timeout time.Duration) (int, string, error) {
@@ -498,6 +1001,194 @@ func (chk TCPTimeout) Status() (int, string, error) {
 	return connectionCheck(chk.name, "TCP", chk.timeout)
 }
 
+/*
+#### PortConcurrency
+Description: Are this many simultaneous TCP connections to this address all
+accepted within this timeout? Catches accept queue and connection limit
+misconfigurations that a single connection won't.
+Parameters:
+  - Address (host:port): Address to connect to
+  - Count (positive int): Number of connections to hold open at once
+  - Timeout (time.Duration): How long to wait for all of them to be accepted
+Example parameters:
+  - "localhost:80", "db.example.com:5432"
+  - 10, 100, 500
+  - 5s, 30s
+*/
+
+type PortConcurrency struct {
+	address string
+	count   int
+	timeout time.Duration
+}
+
+func (chk PortConcurrency) ID() string { return "PortConcurrency" }
+
+func (chk PortConcurrency) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if _, _, err := net.SplitHostPort(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "host:port"}
+	}
+	count, err := strconv.ParseUint(params[1], 10, 16)
+	if err != nil || count < 1 {
+		return chk, errutil.ParameterTypeError{params[1], "positive int"}
+	}
+	timeout, err := time.ParseDuration(params[2])
+	if err != nil || timeout <= 0 {
+		return chk, errutil.ParameterTypeError{params[2], "time.Duration"}
+	}
+	chk.address = params[0]
+	chk.count = int(count)
+	chk.timeout = timeout
+	return chk, nil
+}
+
+func (chk PortConcurrency) Status() (int, string, error) {
+	deadline := time.Now().Add(chk.timeout)
+	conns := make(chan net.Conn, chk.count)
+	errs := make(chan error, chk.count)
+	for i := 0; i < chk.count; i++ {
+		go func() {
+			conn, err := net.DialTimeout("tcp", chk.address, deadline.Sub(time.Now()))
+			if err != nil {
+				errs <- err
+				return
+			}
+			conns <- conn
+		}()
+	}
+	// hold every connection open until all of them have been attempted
+	var failures []string
+	for i := 0; i < chk.count; i++ {
+		select {
+		case conn := <-conns:
+			defer conn.Close()
+		case err := <-errs:
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) < 1 {
+		return errutil.Success()
+	}
+	msg := fmt.Sprintf("%d of %d connections to %s failed",
+		len(failures), chk.count, chk.address)
+	return errutil.GenericError(msg, chk.count, countDuplicates(failures))
+}
+
+// countDuplicates lists each distinct string once, in the order they first
+// appeared, with the number of times it appeared if that was more than once
+func countDuplicates(strs []string) (counted []string) {
+	counts := make(map[string]int)
+	var distinct []string
+	for _, str := range strs {
+		if counts[str] == 0 {
+			distinct = append(distinct, str)
+		}
+		counts[str]++
+	}
+	for _, str := range distinct {
+		if counts[str] > 1 {
+			str += fmt.Sprintf(" (%d times)", counts[str])
+		}
+		counted = append(counted, str)
+	}
+	return counted
+}
+
+// durations implements sort.Interface for a slice of time.Duration
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// percentile returns the pth percentile of samples by the nearest-rank
+// method. samples must be sorted and non-empty.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(samples))))
+	if rank < 1 {
+		rank = 1
+	}
+	return samples[rank-1]
+}
+
+/*
+#### TCPLatencyP95
+Description: Is the 95th percentile of the time taken to open a TCP connection
+to this address at most this long, over this many sequential connections?
+Parameters:
+  - Address (host:port): Address to connect to
+  - Probes (positive int): Number of connections to time
+  - Max (time.Duration): Maximum acceptable 95th percentile connect time
+  - Timeout (time.Duration): How long to wait for each connection
+Example parameters:
+  - "localhost:80", "db.example.com:5432"
+  - 20, 100
+  - 10ms, 200ms
+  - 1s, 5s
+*/
+
+type TCPLatencyP95 struct {
+	address      string
+	probes       int
+	max, timeout time.Duration
+}
+
+func (chk TCPLatencyP95) ID() string { return "TCPLatencyP95" }
+
+func (chk TCPLatencyP95) New(params []string) (chkutil.Check, error) {
+	if len(params) != 4 {
+		return chk, errutil.ParameterLengthError{4, params}
+	} else if _, _, err := net.SplitHostPort(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "host:port"}
+	}
+	probes, err := strconv.ParseUint(params[1], 10, 16)
+	if err != nil || probes < 1 {
+		return chk, errutil.ParameterTypeError{params[1], "positive int"}
+	}
+	max, err := time.ParseDuration(params[2])
+	if err != nil || max <= 0 {
+		return chk, errutil.ParameterTypeError{params[2], "time.Duration"}
+	}
+	timeout, err := time.ParseDuration(params[3])
+	if err != nil || timeout <= 0 {
+		return chk, errutil.ParameterTypeError{params[3], "time.Duration"}
+	}
+	chk.address = params[0]
+	chk.probes = int(probes)
+	chk.max = max
+	chk.timeout = timeout
+	return chk, nil
+}
+
+func (chk TCPLatencyP95) Status() (int, string, error) {
+	var samples []time.Duration
+	failures := 0
+	for i := 0; i < chk.probes; i++ {
+		elapsed, err := netstatus.ConnectTime(chk.address, chk.timeout)
+		if err != nil {
+			failures++
+			continue
+		}
+		samples = append(samples, elapsed)
+	}
+	if failures > 0 {
+		msg := fmt.Sprintf("%d of %d connections to %s failed",
+			failures, chk.probes, chk.address)
+		return 1, msg, nil
+	}
+	sort.Sort(durations(samples))
+	p95 := percentile(samples, 95)
+	if p95 <= chk.max {
+		return errutil.Success()
+	}
+	msg := "95th percentile TCP connect time to " + chk.address + " was too high"
+	msg += " (worst of " + fmt.Sprint(chk.probes) + " was "
+	msg += samples[len(samples)-1].String() + ")"
+	return errutil.GenericError(msg, chk.max, []string{p95.String()})
+}
+
 /*
 #### UDPTimeout
 Description: Like TCPTimeout, but with UDP
@@ -528,31 +1219,331 @@ func (chk UDPTimeout) Status() (int, string, error) {
 	return connectionCheck(chk.name, "UDP", chk.timeout)
 }
 
+// pathMTUTimeout is how long PathMTU waits for the reply to each probe
+var pathMTUTimeout = time.Second
+
+// minMTU is the smallest MTU every IPv4 link must support (RFC 791)
+const minMTU = 68
+
+/*
+#### PathMTU
+Description: Can packets of this size reach this host without being
+fragmented? Sends ICMP echo requests with the don't-fragment bit set.
+Parameters:
+  - Host (string): Hostname or IPv4 address
+  - MTU (int): Packet size in bytes, including the IP header
+Example parameters:
+  - "192.168.0.1", "vpn.example.com"
+  - 1500, 1400, 9000
+Dependencies:
+  - Raw sockets (root or CAP_NET_RAW)
+*/
+
+type PathMTU struct {
+	host string
+	mtu  int
+}
+
+func (chk PathMTU) ID() string { return "PathMTU" }
+
+func (chk PathMTU) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	mtu, err := strconv.ParseUint(params[1], 10, 16)
+	if err != nil || mtu < minMTU {
+		return chk, errutil.ParameterTypeError{params[1], "MTU"}
+	}
+	chk.host = params[0]
+	chk.mtu = int(mtu)
+	return chk, nil
+}
+
+func (chk PathMTU) Status() (int, string, error) {
+	addr, err := net.ResolveIPAddr("ip4", chk.host)
+	if err != nil {
+		return 1, "Couldn't resolve host " + chk.host + ": " + err.Error(), nil
+	}
+	// probe reports whether a packet of this size gets through
+	probe := func(size int) (bool, error) {
+		return netstatus.PingDontFragment(addr.IP, size, pathMTUTimeout)
+	}
+	ok, err := probe(chk.mtu)
+	if err == netstatus.ErrNoRawSockets {
+		return 1, "PathMTU can't probe " + chk.host + ": " + err.Error(), nil
+	} else if err != nil {
+		return 1, "", err
+	} else if ok {
+		return errutil.Success()
+	}
+	if ok, err = probe(minMTU); err != nil {
+		return 1, "", err
+	} else if !ok {
+		return 1, "Host didn't reply to ICMP echo requests: " + chk.host, nil
+	}
+	// binary search for the largest size that gets through
+	largest, tooBig := minMTU, chk.mtu
+	for tooBig-largest > 1 {
+		size := (largest + tooBig) / 2
+		if ok, err = probe(size); err != nil {
+			return 1, "", err
+		} else if ok {
+			largest = size
+		} else {
+			tooBig = size
+		}
+	}
+	msg := "Packets were too big to reach " + chk.host + " unfragmented"
+	return errutil.GenericError(msg, chk.mtu, []string{fmt.Sprint(largest)})
+}
+
+// portProtocolTimeout is how long PortProtocol waits to connect and then for
+// a matching response
+var portProtocolTimeout = 5 * time.Second
+
+/*
+#### PortProtocol
+Description: Does the service at this address answer this probe with a
+response matching this regexp? A lightweight banner grab for protocols
+without dedicated checks.
+Parameters:
+  - Address (host:port): Address to connect to over TCP
+  - Probe (string): What to send after connecting, with Go escapes like \r\n,
+    or "" to just read the service's banner
+  - Regexp (regexp): Regexp the response should match
+Example parameters:
+  - "localhost:22", "mail.example.com:25", "localhost:6379"
+  - "", "EHLO example.com\r\n", "PING\r\n"
+  - "^SSH-2\.0-", "^220 ", "\+PONG"
+*/
+
+type PortProtocol struct {
+	address, probe string
+	re             *regexp.Regexp
+}
+
+func (chk PortProtocol) ID() string { return "PortProtocol" }
+
+func (chk PortProtocol) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	} else if _, _, err := net.SplitHostPort(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "host:port"}
+	}
+	probe, err := strconv.Unquote(`"` + strings.Replace(params[1], `"`, `\"`, -1) + `"`)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[1], "string with Go escapes"}
+	}
+	re, err := regexp.Compile(params[2])
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[2], "regexp"}
+	}
+	chk.address = params[0]
+	chk.probe = probe
+	chk.re = re
+	return chk, nil
+}
+
+func (chk PortProtocol) Status() (int, string, error) {
+	conn, err := net.DialTimeout("tcp", chk.address, portProtocolTimeout)
+	if err != nil {
+		return 1, "Could not connect over TCP to host: " + chk.address, nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(portProtocolTimeout))
+	if chk.probe != "" {
+		if _, err := conn.Write([]byte(chk.probe)); err != nil {
+			return 1, "Could not send probe to " + chk.address + ": " + err.Error(), nil
+		}
+	}
+	// read until the response matches, the service hangs up, or we time out
+	var response []byte
+	buf := make([]byte, 4096)
+	for len(response) < 65536 {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+		if chk.re.Match(response) {
+			return errutil.Success()
+		} else if err != nil {
+			break
+		}
+	}
+	msg := "Response from " + chk.address + " didn't match"
+	return errutil.GenericError(msg, chk.re.String(), []string{strconv.Quote(string(response))})
+}
+
+// tlsTimeout is how long checks wait for TLS handshakes to complete
+var tlsTimeout = 10 * time.Second
+
+// certFingerprint returns the SHA-256 fingerprint of a DER encoded
+// certificate, as colon separated hex bytes
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	var hexBytes []string
+	for _, b := range sum {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02X", b))
+	}
+	return strings.Join(hexBytes, ":")
+}
+
+/*
+#### TLSCertMatchesFile
+Description: Is the certificate served at this address exactly the one in
+this PEM file? Catches services that weren't reloaded after a certificate was
+replaced.
+Parameters:
+  - Address (host:port): Address of the TLS service
+  - Path (filepath): PEM file whose first certificate should be served
+Example parameters:
+  - "localhost:443", "mail.example.com:993"
+  - "/etc/ssl/certs/example.com.pem", "/etc/letsencrypt/live/example.com/cert.pem"
+*/
+
+type TLSCertMatchesFile struct{ address, path string }
+
+func (chk TLSCertMatchesFile) ID() string { return "TLSCertMatchesFile" }
+
+func (chk TLSCertMatchesFile) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if _, _, err := net.SplitHostPort(params[0]); err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "host:port"}
+	}
+	chk.address = params[0]
+	chk.path = params[1]
+	return chk, nil
+}
+
+func (chk TLSCertMatchesFile) Status() (int, string, error) {
+	data, err := ioutil.ReadFile(chk.path)
+	if os.IsNotExist(err) {
+		return 1, "No such file: " + chk.path, nil
+	} else if err != nil {
+		return 1, "", err
+	}
+	var expected []byte
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			expected = block.Bytes
+			break
+		}
+	}
+	if expected == nil {
+		return 1, "No PEM encoded certificate found in " + chk.path, nil
+	} else if _, err := x509.ParseCertificate(expected); err != nil {
+		return 1, "Couldn't parse certificate in " + chk.path + ": " + err.Error(), nil
+	}
+	host, _, _ := net.SplitHostPort(chk.address)
+	dialer := &net.Dialer{Timeout: tlsTimeout}
+	// we compare the certificate itself, so there's no need to verify it
+	config := &tls.Config{ServerName: host, InsecureSkipVerify: true}
+	conn, err := tls.DialWithDialer(dialer, "tcp", chk.address, config)
+	if err != nil {
+		return 1, "Couldn't complete TLS handshake with " + chk.address + ": " + err.Error(), nil
+	}
+	defer conn.Close()
+	served := conn.ConnectionState().PeerCertificates
+	if len(served) < 1 {
+		return 1, "No certificate was served at " + chk.address, nil
+	} else if bytes.Equal(served[0].Raw, expected) {
+		return errutil.Success()
+	}
+	msg := "Certificate served at " + chk.address + " didn't match " + chk.path
+	msg += " (SHA-256 fingerprints)"
+	return errutil.GenericError(msg, certFingerprint(expected),
+		[]string{certFingerprint(served[0].Raw)})
+}
+
+// routeColumns is the number of columns that `route -n` prints
+const routeColumns = 8
+
+// parseRoutingTable splits the output of `route -n` into a table, returning an
+// error if it doesn't have the expected columns
+func parseRoutingTable(out string) (tabular.Table, error) {
+	// the first line is a title: "Kernel IP routing table"
+	lines := tabular.Lines(strings.TrimSpace(out))
+	if len(lines) < 2 {
+		return nil, errors.New("Routing table had no headers: " + out)
+	}
+	return tabular.SplitWithWidth(tabular.Unlines(lines[1:]), routeColumns)
+}
+
 // returns a column of the routing table as a slice of strings
 // TODO read from /proc/net/route instead
-func RoutingTableColumn(name string) []string {
+func RoutingTableColumn(name string) ([]string, error) {
 	cmd := exec.Command("route", "-n")
-	out := chkutil.CommandOutput(cmd)
-	table := tabular.ProbabalisticSplit(out)
-	if len(table) < 1 {
-		log.WithFields(log.Fields{
-			"column": name,
-			"table":  "\n" + tabular.ToString(table),
-		}).Fatal("Routing table was not available or not properly parsed")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.New(err.Error() + ": output: " + string(out))
+	}
+	table, err := parseRoutingTable(string(out))
+	if err != nil {
+		return nil, errors.New("Couldn't parse routing table: " + err.Error())
 	}
-	finalTable := table[1:] // has extra line before headers
-	return tabular.GetColumnByHeader(name, finalTable)
+	return tabular.GetColumnByHeaderMatching(name, table, tabular.TolerantHeaders)
 }
 
 // RoutingTableMatch asks: Is this value in this column of the routing table?
 func RoutingTableMatch(col string, str string) (int, string, error) {
-	column := RoutingTableColumn(col)
-	if tabular.StrIn(str, column) {
+	column, err := RoutingTableColumn(col)
+	if err != nil {
+		return 1, "", err
+	} else if tabular.StrIn(str, column) {
 		return errutil.Success()
 	}
 	return errutil.GenericError("Not found in routing table", str, column)
 }
 
+/*
+#### RouteCount
+Description: Does the number of entries in the kernel's IPv4 routing table
+satisfy this comparison?
+Parameters:
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare the route count against
+Example parameters:
+  - "<", ">="
+  - 1, 50, 1000
+Dependencies:
+  - /proc/net/route
+*/
+
+type RouteCount struct {
+	operator string
+	count    uint64
+}
+
+func (chk RouteCount) ID() string { return "RouteCount" }
+
+func (chk RouteCount) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if !chkutil.ValidOperator(params[0]) {
+		return chk, errutil.ParameterTypeError{params[0], "operator"}
+	}
+	count, err := strconv.ParseUint(params[1], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[1], "positive int"}
+	}
+	chk.operator = params[0]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk RouteCount) Status() (int, string, error) {
+	routes, err := netstatus.RoutingTable()
+	if err != nil {
+		return 1, "", err
+	}
+	actual := len(routes)
+	if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := "Routing table had " + fmt.Sprint(actual) + " routes"
+	expected := chk.operator + " " + fmt.Sprint(chk.count)
+	return errutil.GenericError(msg, expected, []string{fmt.Sprint(actual)})
+}
+
 /*
 #### RoutingTableDestination
 Description: Is this IP address in the kernel's IP routing table?
@@ -713,3 +1704,239 @@ func (chk ResponseMatchesInsecure) New(params []string) (chkutil.Check, error) {
 func (chk ResponseMatchesInsecure) Status() (int, string, error) {
 	return ResponseMatchesGeneral(chk.urlstr, chk.re, false)
 }
+
+// ufwStatus parses the output of `ufw status verbose`, reporting whether the
+// firewall is active and denies (or rejects) incoming traffic by default
+func ufwStatus(out string) (active bool, defaultDeny bool) {
+	active = regexp.MustCompile(`(?m)^Status:\s*active`).MatchString(out)
+	denyRe := regexp.MustCompile(`(?m)^Default:.*(deny|reject) \(incoming\)`)
+	return active, denyRe.MatchString(out)
+}
+
+// nftDefaultDeny reports whether an nftables ruleset (as printed by
+// `nft list ruleset`) has an input chain that drops packets by default
+func nftDefaultDeny(ruleset string) bool {
+	for _, line := range strings.Split(ruleset, "\n") {
+		if strings.Contains(line, "hook input") && strings.Contains(line, "policy drop") {
+			return true
+		}
+	}
+	return false
+}
+
+// firewall is a firewall framework that FirewallEnabled can detect
+type firewall struct {
+	name, executable string
+	// problem explains why the firewall isn't enabled with a default-deny
+	// posture, or returns "" if it is
+	problem func() string
+}
+
+// firewallOutput runs a command, treating non-zero exit codes as information
+func firewallOutput(name string, args ...string) string {
+	out, _ := exec.Command(name, args...).CombinedOutput()
+	return strings.TrimSpace(string(out))
+}
+
+// firewalldProblem checks the output of `firewall-cmd --state` and
+// `firewall-cmd --get-default-zone`
+func firewalldProblem(state, zone string) string {
+	if state != "running" {
+		return "not running: " + state
+	} else if zone == "trusted" {
+		return "accepts all traffic in zone: " + zone
+	}
+	return ""
+}
+
+// ufwProblem checks the output of `ufw status verbose`
+func ufwProblem(out string) string {
+	active, defaultDeny := ufwStatus(out)
+	if !active {
+		return "not active: " + out
+	} else if !defaultDeny {
+		return "does not deny incoming traffic by default"
+	}
+	return ""
+}
+
+// nftProblem checks the output of `nft list ruleset`
+func nftProblem(ruleset string, err error) string {
+	if err != nil {
+		return "couldn't list ruleset: " + err.Error() + ": " + ruleset
+	} else if strings.TrimSpace(ruleset) == "" {
+		return "no rules loaded"
+	} else if !nftDefaultDeny(ruleset) {
+		return "no input chain with a drop policy"
+	}
+	return ""
+}
+
+var firewalls = []firewall{
+	{"firewalld", "firewall-cmd", func() string {
+		return firewalldProblem(firewallOutput("firewall-cmd", "--state"),
+			firewallOutput("firewall-cmd", "--get-default-zone"))
+	}},
+	{"ufw", "ufw", func() string {
+		return ufwProblem(firewallOutput("ufw", "status", "verbose"))
+	}},
+	{"nftables", "nft", func() string {
+		out, err := exec.Command("nft", "list", "ruleset").CombinedOutput()
+		return nftProblem(string(out), err)
+	}},
+}
+
+// firewallsEnabled passes if any of the installed firewalls is enabled, and
+// otherwise reports the state of each of them
+func firewallsEnabled(fws []firewall) (int, string, error) {
+	var detected []string
+	for _, fw := range fws {
+		if _, err := exec.LookPath(fw.executable); err != nil {
+			continue
+		}
+		problem := fw.problem()
+		if problem == "" {
+			return errutil.Success()
+		}
+		detected = append(detected, "Firewall "+fw.name+": "+problem)
+	}
+	if len(detected) < 1 {
+		return 1, "No firewall framework found (firewalld, ufw, nftables)", nil
+	}
+	msg := "No firewall was enabled with a default-deny posture:\n\t"
+	return 1, msg + strings.Join(detected, "\n\t"), nil
+}
+
+/*
+#### FirewallEnabled
+Description: Is a firewall running with a default-deny posture? Checks each of
+firewalld, ufw, and nftables that is installed, and passes if any of them is
+enabled. For firewalld, the default zone must not be "trusted", for ufw,
+incoming traffic must be denied by default, and for nftables, an input chain
+must have a drop policy.
+Parameters: None
+Dependencies:
+  - firewall-cmd, ufw, or nft
+*/
+
+type FirewallEnabled struct{}
+
+func (chk FirewallEnabled) ID() string { return "FirewallEnabled" }
+
+func (chk FirewallEnabled) New(params []string) (chkutil.Check, error) {
+	if len(params) != 0 {
+		return chk, errutil.ParameterLengthError{0, params}
+	}
+	return chk, nil
+}
+
+func (chk FirewallEnabled) Status() (int, string, error) {
+	return firewallsEnabled(firewalls)
+}
+
+// iptablesDrops sums the packets dropped by DROP rules and chain policies in
+// the output of `iptables -nvxL`
+func iptablesDrops(out string) (drops uint64) {
+	policyRe := regexp.MustCompile(`\(policy DROP (\d+) packets`)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if match := policyRe.FindStringSubmatch(line); match != nil {
+			packets, _ := strconv.ParseUint(match[1], 10, 64)
+			drops += packets
+		} else if len(fields) > 2 && fields[2] == "DROP" {
+			packets, _ := strconv.ParseUint(fields[0], 10, 64)
+			drops += packets
+		}
+	}
+	return drops
+}
+
+// nftDrops sums the packets counted by dropping rules in the output of
+// `nft list ruleset`. Only rules with a counter are included.
+func nftDrops(ruleset string) (drops uint64) {
+	counterRe := regexp.MustCompile(`counter packets (\d+) bytes \d+.*\bdrop\b`)
+	for _, line := range strings.Split(ruleset, "\n") {
+		if match := counterRe.FindStringSubmatch(line); match != nil {
+			packets, _ := strconv.ParseUint(match[1], 10, 64)
+			drops += packets
+		}
+	}
+	return drops
+}
+
+// firewallDrops returns the number of packets the firewall has dropped so far,
+// using iptables if it is installed, and nftables otherwise
+func firewallDrops() (uint64, error) {
+	name, args, parse := "iptables", []string{"-nvxL"}, iptablesDrops
+	if _, err := exec.LookPath(name); err != nil {
+		name, args, parse = "nft", []string{"list", "ruleset"}, nftDrops
+		if _, err := exec.LookPath(name); err != nil {
+			return 0, errors.New("Neither iptables nor nft is installed")
+		}
+	}
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return 0, errors.New(err.Error() + ": output: " + string(out))
+	}
+	return parse(string(out)), nil
+}
+
+/*
+#### FirewallDrops
+Description: Did the firewall drop at most this many packets over this
+sampling window?
+Parameters:
+  - Max (int): Maximum number of dropped packets
+  - Window (time.Duration): How long to count dropped packets for
+Example parameters:
+  - 0, 100, 5000
+  - 1s, 10s, 1m
+Dependencies:
+  - iptables or nft
+*/
+
+type FirewallDrops struct {
+	max    uint64
+	window time.Duration
+}
+
+func (chk FirewallDrops) ID() string { return "FirewallDrops" }
+
+func (chk FirewallDrops) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	max, err := strconv.ParseUint(params[0], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[0], "positive int"}
+	}
+	window, err := time.ParseDuration(params[1])
+	if err != nil || window <= 0 {
+		return chk, errutil.ParameterTypeError{params[1], "time.Duration"}
+	}
+	chk.max = max
+	chk.window = window
+	return chk, nil
+}
+
+func (chk FirewallDrops) Status() (int, string, error) {
+	before, err := firewallDrops()
+	if err != nil {
+		return 1, "", err
+	}
+	time.Sleep(chk.window)
+	after, err := firewallDrops()
+	if err != nil {
+		return 1, "", err
+	}
+	// counters can be reset in the meantime
+	var drops uint64
+	if after > before {
+		drops = after - before
+	}
+	if drops <= chk.max {
+		return errutil.Success()
+	}
+	msg := "Firewall dropped too many packets in " + chk.window.String()
+	return errutil.GenericError(msg, chk.max, []string{fmt.Sprint(drops)})
+}
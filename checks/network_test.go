@@ -1,7 +1,26 @@
 package checks
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/zeldal/distributive/tabular"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 var validHosts = [][]string{
@@ -53,6 +72,189 @@ func TestPortUDP(t *testing.T) {
 	testCheck([][]string{}, closedPorts, PortUDP{}, t)
 }
 
+func TestProcessListensOn(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"nginx", "80,443"}, {"sshd", "22"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"nginx", "80,"}, {"nginx", "http"}, {"nginx", "70000"},
+	}...)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	self := filepath.Base(os.Args[0])
+	goodEggs := [][]string{{self, port}}
+	badEggs := [][]string{
+		{self, port + ",5310"}, {"lkjashldfb", port}, {self, "49151"},
+	}
+	testParameters(validInputs, invalidInputs, ProcessListensOn{}, t)
+	testCheck(goodEggs, badEggs, ProcessListensOn{}, t)
+}
+
+func TestCloseWaitSockets(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"0", ""}, {"10", "80"}, {"100", "8080"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"-1", ""}, {"many", ""}, {"0", "http"}, {"0", "70000"},
+	}...)
+	// leave a connection in CLOSE_WAIT by never closing our end
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client.Close()
+	time.Sleep(50 * time.Millisecond)
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	goodEggs := [][]string{{"1", port}, {"0", "49151"}}
+	badEggs := [][]string{{"0", port}, {"0", ""}}
+	testParameters(validInputs, invalidInputs, CloseWaitSockets{}, t)
+	testCheck(goodEggs, badEggs, CloseWaitSockets{}, t)
+}
+
+func TestUfwStatus(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"Status: active\nLogging: on (low)\nDefault: deny (incoming), allow (outgoing), disabled (routed)\n",
+		"Status: active\nDefault: allow (incoming), allow (outgoing), disabled (routed)\n",
+		"Status: inactive\n",
+	}
+	outputs := [][2]bool{{true, true}, {true, false}, {false, false}}
+	for i := range inputs {
+		active, deny := ufwStatus(inputs[i])
+		if active != outputs[i][0] || deny != outputs[i][1] {
+			t.Errorf("ufwStatus(%q) = %v, %v", inputs[i], active, deny)
+		}
+	}
+}
+
+func TestNftDefaultDeny(t *testing.T) {
+	t.Parallel()
+	deny := "table inet filter {\n\tchain input {\n\t\ttype filter hook input priority filter; policy drop;\n\t}\n}\n"
+	accept := "table inet filter {\n\tchain input {\n\t\ttype filter hook input priority filter; policy accept;\n\t}\n\tchain forward {\n\t\ttype filter hook forward priority filter; policy drop;\n\t}\n}\n"
+	if !nftDefaultDeny(deny) {
+		t.Error("nftDefaultDeny didn't detect drop policy")
+	} else if nftDefaultDeny(accept) || nftDefaultDeny("") {
+		t.Error("nftDefaultDeny detected nonexistent drop policy")
+	}
+}
+
+func TestFirewallProblems(t *testing.T) {
+	t.Parallel()
+	problems := []string{
+		firewalldProblem("running", "public"),
+		ufwProblem("Status: active\nDefault: deny (incoming), allow (outgoing)\n"),
+		nftProblem("table inet filter {\n\tchain input {\n\t\ttype filter hook input priority filter; policy drop;\n\t}\n}\n", nil),
+	}
+	for _, problem := range problems {
+		if problem != "" {
+			t.Errorf("Unexpected problem with an enabled firewall: %s", problem)
+		}
+	}
+	problems = []string{
+		firewalldProblem("not running", "public"),
+		firewalldProblem("running", "trusted"),
+		ufwProblem("Status: inactive\n"),
+		ufwProblem("Status: active\nDefault: allow (incoming), allow (outgoing)\n"),
+		nftProblem("", nil),
+		nftProblem("table inet filter {\n}\n", nil),
+		nftProblem("Operation not permitted", errors.New("exit status 1")),
+	}
+	for i, problem := range problems {
+		if problem == "" {
+			t.Errorf("Disabled firewall %d had no problem", i)
+		}
+	}
+}
+
+func TestFirewallsEnabled(t *testing.T) {
+	t.Parallel()
+	ok := func() string { return "" }
+	off := func() string { return "not active" }
+	// sh is installed everywhere, steppenwolf nowhere
+	inputs := [][]firewall{
+		{{"a", "sh", ok}},
+		{{"a", "sh", off}, {"b", "sh", ok}},
+		{{"a", "steppenwolf", off}, {"b", "sh", ok}},
+		{{"a", "sh", off}, {"b", "steppenwolf", ok}},
+		{{"a", "sh", off}, {"b", "sh", off}},
+		{{"a", "steppenwolf", ok}},
+		{},
+	}
+	outputs := []int{0, 0, 0, 1, 1, 1, 1}
+	for i := range inputs {
+		code, msg, err := firewallsEnabled(inputs[i])
+		if err != nil {
+			t.Error(err)
+		} else if code != outputs[i] {
+			t.Errorf("Expected exit code %d for firewalls %d, got %d: %s",
+				outputs[i], i, code, msg)
+		}
+	}
+	_, msg, _ := firewallsEnabled(inputs[4])
+	if !strings.Contains(msg, "Firewall a: not active") ||
+		!strings.Contains(msg, "Firewall b: not active") {
+		t.Errorf("Message didn't report each firewall's state: %s", msg)
+	}
+}
+
+func TestFirewallEnabled(t *testing.T) {
+	t.Parallel()
+	testParameters([][]string{{}}, names, FirewallEnabled{}, t)
+}
+
+func TestIptablesDrops(t *testing.T) {
+	t.Parallel()
+	out := `Chain INPUT (policy DROP 12 packets, 720 bytes)
+    pkts      bytes target     prot opt in     out     source               destination
+      10      600 DROP       all  --  *      *       192.0.2.4            0.0.0.0/0
+     500    30000 ACCEPT     tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpt:22
+
+Chain FORWARD (policy ACCEPT 0 packets, 0 bytes)
+    pkts      bytes target     prot opt in     out     source               destination
+       3      180 DROP       all  --  *      *       0.0.0.0/0            0.0.0.0/0
+`
+	if drops := iptablesDrops(out); drops != 25 {
+		t.Errorf("iptablesDrops counted %d drops, expected 25", drops)
+	}
+}
+
+func TestNftDrops(t *testing.T) {
+	t.Parallel()
+	ruleset := `table inet filter {
+	chain input {
+		type filter hook input priority filter; policy drop;
+		ip saddr 192.0.2.4 counter packets 7 bytes 420 drop
+		tcp dport 22 counter packets 500 bytes 30000 accept
+		counter packets 2 bytes 120 drop
+	}
+}
+`
+	if drops := nftDrops(ruleset); drops != 9 {
+		t.Errorf("nftDrops counted %d drops, expected 9", drops)
+	}
+}
+
+func TestFirewallDrops(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"0", "1s"}, {"100", "10ms"}, {"5000", "1m"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"-1", "1s"}, {"many", "1s"}, {"0", "1"}, {"0", "-1s"},
+	}...)
+	testParameters(validInputs, invalidInputs, FirewallDrops{}, t)
+}
+
 func TestInterfaceExists(t *testing.T) {
 	t.Parallel()
 	validInputs := names
@@ -63,6 +265,55 @@ func TestInterfaceExists(t *testing.T) {
 	testCheck(goodEggs, badEggs, InterfaceExists{}, t)
 }
 
+func TestInterfaceThroughput(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"lo", "rx", "10mb", "1s"}, {"eth0", "TX", "1gb", "10ms"},
+	}
+	invalidInputs := [][]string{
+		{}, {"lo", "rx", "10mb"}, {"lo", "in", "10mb", "1s"},
+		{"lo", "rx", "lots", "1s"}, {"lo", "rx", "10mb", "1"},
+		{"lo", "rx", "10mb", "0s"},
+	}
+	goodEggs := [][]string{{"lo", "rx", "1tb", "10ms"}}
+	badEggs := [][]string{{"lkjashldfb", "rx", "1tb", "10ms"}}
+	testParameters(validInputs, invalidInputs, InterfaceThroughput{}, t)
+	testCheck(goodEggs, badEggs, InterfaceThroughput{}, t)
+}
+
+func TestInterfaceDuplex(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"eth0", "full"}, {"lo", "HALF"}}
+	invalidInputs := append(notLengthTwo, []string{"eth0", "both"})
+	goodEggs := [][]string{}
+	badEggs := [][]string{{"lo", "full"}, {"lkjashldfb", "full"}}
+	testParameters(validInputs, invalidInputs, InterfaceDuplex{}, t)
+	testCheck(goodEggs, badEggs, InterfaceDuplex{}, t)
+}
+
+func TestDHCPLease(t *testing.T) {
+	t.Parallel()
+	validInputs := names
+	invalidInputs := notLengthOne
+	goodEggs := [][]string{}
+	// the loopback interface is never configured by DHCP
+	badEggs := [][]string{{"lo"}, {"lkjashldfb"}}
+	testParameters(validInputs, invalidInputs, DHCPLease{}, t)
+	testCheck(goodEggs, badEggs, DHCPLease{}, t)
+}
+
+func TestVLANInterface(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"eth0.100", "100"}, {"vlan10", "10"}, {"lo", "0"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"eth0.100", "-1"}, {"eth0.100", "4096"}, {"eth0.100", "ten"},
+	}...)
+	goodEggs := [][]string{}
+	badEggs := [][]string{{"lo", "0"}, {"lkjashldfb", "100"}}
+	testParameters(validInputs, invalidInputs, VLANInterface{}, t)
+	testCheck(goodEggs, badEggs, VLANInterface{}, t)
+}
+
 func TestUp(t *testing.T) {
 	t.Parallel()
 	validInputs := names
@@ -83,6 +334,20 @@ func TestIP4(t *testing.T) {
 	testCheck(goodEggs, badEggs, IP4{}, t)
 }
 
+func TestLoopbackAliases(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"127.0.0.1"}, {"127.0.0.2,127.0.1.1"}, {"127.0.0.1, ::1"},
+	}
+	invalidInputs := append(notLengthOne, [][]string{
+		{""}, {"127.0.0.1,"}, {"localhost"}, {"127.0.0.1,256.0.0.1"},
+	}...)
+	goodEggs := [][]string{{"127.0.0.1"}}
+	badEggs := [][]string{{"127.0.0.1,192.0.2.1"}, {"198.51.100.7"}}
+	testParameters(validInputs, invalidInputs, LoopbackAliases{}, t)
+	testCheck(goodEggs, badEggs, LoopbackAliases{}, t)
+}
+
 func TestIP6(t *testing.T) {
 	t.Parallel()
 	validInputs := appendParameter(names, "0000:000:0000:000:0000:0000:000:0000")
@@ -111,6 +376,37 @@ func TestHost(t *testing.T) {
 	testCheck(goodEggs, badEggs, Host{}, t)
 }
 
+func TestParseResolvConf(t *testing.T) {
+	t.Parallel()
+	data := "# Generated by NetworkManager\nsearch example.com corp.example.com\n" +
+		"domain ignored.example.com\nsearch example.com corp.example.com\n" +
+		"nameserver 127.0.0.53\noptions edns0\n"
+	domains, stub := parseResolvConf(data)
+	if fmt.Sprint(domains) != "[example.com corp.example.com]" || !stub {
+		t.Errorf("Misparsed resolv.conf: %v, %v", domains, stub)
+	}
+	if _, stub := parseResolvConf("nameserver 8.8.8.8\n"); stub {
+		t.Error("Mistook a remote nameserver for the systemd-resolved stub")
+	}
+	out := "Global: example.com\nLink 2 (eth0): corp.example.com ~.\nLink 3 (wg0):\n"
+	domains = parseResolvectlDomains(out)
+	if fmt.Sprint(domains) != "[example.com corp.example.com]" {
+		t.Errorf("Misparsed resolvectl domains: %v", domains)
+	}
+}
+
+func TestDNSSearchDomain(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"example.com"}, {"corp.example.com."}}
+	invalidInputs := append(notLengthOne, [][]string{
+		{""}, {"example.com corp.example.com"}, {"example.com,corp.example.com"},
+	}...)
+	goodEggs := [][]string{}
+	badEggs := [][]string{{"lkjashldfb.invalid"}}
+	testParameters(validInputs, invalidInputs, DNSSearchDomain{}, t)
+	testCheck(goodEggs, badEggs, DNSSearchDomain{}, t)
+}
+
 func TestTCP(t *testing.T) {
 	t.Parallel()
 	testParameters(names, notLengthOne, TCP{}, t)
@@ -132,6 +428,143 @@ func TestTCPTimeout(t *testing.T) {
 	testCheck(goodEggs, badEggs, TCPTimeout{}, t)
 }
 
+func TestPortConcurrency(t *testing.T) {
+	t.Parallel()
+	listener := serveTCP(t, "")
+	defer listener.Close()
+	address := listener.Addr().String()
+	validInputs := [][]string{{address, "10", "5s"}, {"localhost:80", "500", "30s"}}
+	invalidInputs := [][]string{
+		{}, {address}, {address, "10"}, {address, "10", "5s", "5s"},
+		{"localhost", "10", "5s"}, {address, "0", "5s"}, {address, "ten", "5s"},
+		{address, "10", "5"}, {address, "10", "0s"},
+	}
+	goodEggs := [][]string{{address, "1", "5s"}, {address, "20", "5s"}}
+	badEggs := [][]string{{"localhost:1", "5", "5s"}, {address, "5", "1ns"}}
+	testParameters(validInputs, invalidInputs, PortConcurrency{}, t)
+	testCheck(goodEggs, badEggs, PortConcurrency{}, t)
+}
+
+func TestCountDuplicates(t *testing.T) {
+	t.Parallel()
+	input := []string{"refused", "timeout", "refused", "refused", "reset"}
+	expected := []string{"refused (3 times)", "timeout", "reset"}
+	if actual := countDuplicates(input); !tabular.SliceEqual(actual, expected) {
+		t.Errorf("countDuplicates(%v) = %v, expected %v", input, actual, expected)
+	}
+	if actual := countDuplicates(nil); len(actual) != 0 {
+		t.Errorf("countDuplicates(nil) = %v", actual)
+	}
+	// the same error from every connection is only reported once
+	chk, err := PortConcurrency{}.New([]string{"localhost:1", "50", "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, _ := chk.Status(); !strings.Contains(msg, "(50 times)") ||
+		strings.Count(msg, "refused") != 1 {
+		t.Errorf("Identical errors weren't grouped:\n%s", msg)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	var samples []time.Duration
+	for i := 1; i <= 20; i++ {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	tests := map[float64]time.Duration{
+		95: 19 * time.Millisecond, 100: 20 * time.Millisecond,
+		50: 10 * time.Millisecond, 0: time.Millisecond,
+	}
+	for p, expected := range tests {
+		if actual := percentile(samples, p); actual != expected {
+			t.Errorf("Expected percentile %v to be %v, got %v", p, expected, actual)
+		}
+	}
+	if actual := percentile(samples[:1], 95); actual != time.Millisecond {
+		t.Errorf("Expected the only sample, got %v", actual)
+	}
+}
+
+func TestTCPLatencyP95(t *testing.T) {
+	t.Parallel()
+	listener := serveTCP(t, "")
+	defer listener.Close()
+	address := listener.Addr().String()
+	validInputs := [][]string{
+		{address, "20", "10ms", "1s"}, {"localhost:80", "100", "200ms", "5s"},
+	}
+	invalidInputs := [][]string{
+		{}, {address}, {address, "20", "10ms"}, {address, "20", "10ms", "1s", "1s"},
+		{"localhost", "20", "10ms", "1s"}, {address, "0", "10ms", "1s"},
+		{address, "20", "10", "1s"}, {address, "20", "10ms", "0s"},
+	}
+	goodEggs := [][]string{{address, "20", "5s", "5s"}, {address, "1", "1m", "5s"}}
+	badEggs := [][]string{
+		{address, "5", "1ns", "5s"}, {"localhost:1", "3", "1s", "1s"},
+	}
+	testParameters(validInputs, invalidInputs, TCPLatencyP95{}, t)
+	testCheck(goodEggs, badEggs, TCPLatencyP95{}, t)
+}
+
+func TestTLSCertMatchesFile(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	other := httptest.NewUnstartedServer(handler)
+	other.TLS = &tls.Config{Certificates: []tls.Certificate{otherCertificate(t)}}
+	other.StartTLS()
+	defer other.Close()
+	// writePEM writes the certificate of this server to a PEM file in dir
+	writePEM := func(name string, server *httptest.Server) string {
+		path := filepath.Join(dir, name)
+		block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	served := writePEM("served.pem", server)
+	otherPEM := writePEM("other.pem", other)
+	address := server.Listener.Addr().String()
+	validInputs := [][]string{{address, served}, {"localhost:443", "/etc/ssl/cert.pem"}}
+	invalidInputs := append(notLengthOne, []string{"localhost", served})
+	goodEggs := [][]string{{address, served}, {other.Listener.Addr().String(), otherPEM}}
+	badEggs := [][]string{
+		{address, otherPEM}, {address, "/steppenwolf.pem"}, {address, "/dev/null"},
+		{"localhost:1", served},
+	}
+	testParameters(validInputs, invalidInputs, TLSCertMatchesFile{}, t)
+	testCheck(goodEggs, badEggs, TLSCertMatchesFile{}, t)
+}
+
+// otherCertificate generates a self-signed certificate for localhost, different
+// from the one httptest servers use by default
+func otherCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
 func TestUDPTimeout(t *testing.T) {
 	t.Parallel()
 	goodEggs := appendParameter(validHostsWithPort, "5s")
@@ -141,6 +574,85 @@ func TestUDPTimeout(t *testing.T) {
 	testCheck(goodEggs, badEggs, UDPTimeout{}, t)
 }
 
+func TestPortProtocol(t *testing.T) {
+	t.Parallel()
+	listener := serveTCP(t, "+PONG\r\n")
+	defer listener.Close()
+	address := listener.Addr().String()
+	validInputs := [][]string{
+		{"localhost:22", "", "^SSH-2\\.0-"}, {address, "PING\\r\\n", "\\+PONG"},
+		{address, `say "hi"\n`, "hi"},
+	}
+	invalidInputs := [][]string{
+		{}, {address}, {address, "PING"}, {address, "PING", "PONG", "PONG"},
+		{"localhost", "", "^SSH"}, {address, "PING\\q", "PONG"},
+		{address, "PING", "(PONG"},
+	}
+	goodEggs := [][]string{
+		{address, "PING\\r\\n", "\\+PONG"}, {address, "PING", "^\\+PONG\r\n$"},
+	}
+	badEggs := [][]string{
+		{address, "PING\\r\\n", "-ERR"}, {"localhost:1", "PING", "PONG"},
+	}
+	testParameters(validInputs, invalidInputs, PortProtocol{}, t)
+	testCheck(goodEggs, badEggs, PortProtocol{}, t)
+}
+
+func TestPathMTU(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"localhost", "1500"}, {"192.0.2.1", "68"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"localhost", "67"}, {"localhost", "65536"}, {"localhost", "big"},
+	}...)
+	goodEggs := [][]string{{"127.0.0.1", "1500"}, {"localhost", "9000"}}
+	badEggs := [][]string{{"lkjashldfb.com", "1500"}}
+	if os.Geteuid() != 0 {
+		goodEggs = [][]string{} // raw sockets need CAP_NET_RAW
+	}
+	testParameters(validInputs, invalidInputs, PathMTU{}, t)
+	testCheck(goodEggs, badEggs, PathMTU{}, t)
+}
+
+func TestRouteCount(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"<", "1000"}, {">=", "0"}, {"==", "2"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"=", "1000"}, {"<", "-1"}, {"<", "many"},
+	}...)
+	goodEggs := [][]string{{">=", "0"}, {"<", "1000000"}}
+	badEggs := [][]string{{"<", "0"}, {">", "1000000"}}
+	testParameters(validInputs, invalidInputs, RouteCount{}, t)
+	testCheck(goodEggs, badEggs, RouteCount{}, t)
+}
+
+func TestParseRoutingTable(t *testing.T) {
+	t.Parallel()
+	out := `Kernel IP routing table
+Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
+0.0.0.0         10.0.2.2        0.0.0.0         UG    100    0        0 enp0s3
+172.17.0.0      0.0.0.0         255.255.0.0     U     0      0        0 docker0
+`
+	table, err := parseRoutingTable(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"enp0s3", "docker0"}
+	if actual := tabular.GetColumnByHeader("Iface", table); !tabular.SliceEqual(actual, expected) {
+		t.Errorf("Expected Iface column %v, got %v", expected, actual)
+	}
+	// a row is missing its interface, and the flags of another are split
+	ragged := `Kernel IP routing table
+Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
+0.0.0.0         10.0.2.2        0.0.0.0         UG    100    0        0
+172.17.0.0      0.0.0.0         255.255.0.0     U H   0      0        0 docker0
+`
+	for _, input := range []string{ragged, "", "Kernel IP routing table"} {
+		if table, err := parseRoutingTable(input); err == nil {
+			t.Errorf("parseRoutingTable didn't fail on %q: %v", input, table)
+		}
+	}
+}
+
 func TestRoutingTableDestination(t *testing.T) {
 	t.Parallel()
 	// TODO get a list of valid IP addresses for these valid params
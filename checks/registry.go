@@ -0,0 +1,277 @@
+package checks
+
+import (
+	"fmt"
+	"github.com/zeldal/distributive/chkutil"
+	"strings"
+)
+
+// lookupCheck returns the zero value of the check with the given ID, ignoring
+// case, or nil if there's no such check
+func lookupCheck(id string) chkutil.Check {
+	switch strings.ToLower(id) {
+	/***************** composite.go *****************/
+	case "allof":
+		return AllOf{}
+	case "anyof":
+		return AnyOf{}
+		/***************** docker.go *****************/
+	case "dockerimage":
+		return DockerImage{}
+	case "dockerimageregexp":
+		return DockerImageRegexp{}
+	case "dockerrunning":
+		return DockerRunning{}
+	case "dockerrunningapi":
+		return DockerRunningAPI{}
+	case "dockerrunningregexp":
+		return DockerRunningRegexp{}
+		/***************** filesystem.go *****************/
+	case "file":
+		return File{}
+	case "directory":
+		return Directory{}
+	case "symlink":
+		return Symlink{}
+	case "checksum":
+		return Checksum{}
+	case "filematches":
+		return FileMatches{}
+	case "fileheadmatches":
+		return FileHeadMatches{}
+	case "filetailmatches":
+		return FileTailMatches{}
+	case "filestringcount":
+		return FileStringCount{}
+	case "filenotcontains":
+		return FileNotContains{}
+	case "permissions":
+		return Permissions{}
+	case "globcount":
+		return GlobCount{}
+	case "newestfileage":
+		return NewestFileAge{}
+	case "heartbeat":
+		return Heartbeat{}
+	case "directorysize":
+		return DirectorySize{}
+	case "filesidentical":
+		return FilesIdentical{}
+	case "allmountshaveoption":
+		return AllMountsHaveOption{}
+	case "dotenvvar":
+		return DotenvVar{}
+	case "zonerecordcount":
+		return ZoneRecordCount{}
+		/***************** misc.go *****************/
+	case "command":
+		return Command{}
+	case "executableinpath":
+		return ExecutableInPath{}
+	case "commandoutputmatches":
+		return CommandOutputMatches{}
+	case "commandoutputmatchcount":
+		return CommandOutputMatchCount{}
+	case "commandoutputscompare":
+		return CommandOutputsCompare{}
+	case "commandmatchesgolden":
+		return CommandMatchesGolden{}
+	case "commandstable":
+		return CommandStable{}
+	case "commandoutputsorted":
+		return CommandOutputSorted{}
+	case "commandcolumnunique":
+		return CommandColumnUnique{}
+	case "commandcolumncontains":
+		return CommandColumnContains{}
+	case "running":
+		return Running{}
+	case "temp":
+		return Temp{}
+	case "module":
+		return Module{}
+	case "kernelparameter":
+		return KernelParameter{}
+	case "kernelcmdline":
+		return KernelCmdline{}
+	case "boottime":
+		return BootTime{}
+	case "phpconfig":
+		return PHPConfig{}
+	case "hostname":
+		return Hostname{}
+	case "machineid":
+		return MachineID{}
+	case "machineidnot":
+		return MachineIDNot{}
+		/***************** network.go *****************/
+	case "port":
+		return Port{}
+	case "porttcp":
+		return PortTCP{}
+	case "portudp":
+		return PortUDP{}
+	case "processlistenson":
+		return ProcessListensOn{}
+	case "closewaitsockets":
+		return CloseWaitSockets{}
+	case "firewallenabled":
+		return FirewallEnabled{}
+	case "firewalldrops":
+		return FirewallDrops{}
+	case "interfaceexists":
+		return InterfaceExists{}
+	case "interfacethroughput":
+		return InterfaceThroughput{}
+	case "interfaceduplex":
+		return InterfaceDuplex{}
+	case "dhcplease":
+		return DHCPLease{}
+	case "vlaninterface":
+		return VLANInterface{}
+	case "up":
+		return Up{}
+	case "ip4":
+		return IP4{}
+	case "ip6":
+		return IP6{}
+	case "loopbackaliases":
+		return LoopbackAliases{}
+	case "gateway":
+		return Gateway{}
+	case "gatewayinterface":
+		return GatewayInterface{}
+	case "host":
+		return Host{}
+	case "dnssearchdomain":
+		return DNSSearchDomain{}
+	case "tcp":
+		return TCP{}
+	case "udp":
+		return UDP{}
+	case "tcptimeout":
+		return TCPTimeout{}
+	case "tcplatencyp95":
+		return TCPLatencyP95{}
+	case "portconcurrency":
+		return PortConcurrency{}
+	case "udptimeout":
+		return UDPTimeout{}
+	case "portprotocol":
+		return PortProtocol{}
+	case "pathmtu":
+		return PathMTU{}
+	case "tlscertmatchesfile":
+		return TLSCertMatchesFile{}
+	case "routecount":
+		return RouteCount{}
+	case "routingtabledestination":
+		return RoutingTableDestination{}
+	case "routingtableinterface":
+		return RoutingTableInterface{}
+	case "routingtablegateway":
+		return RoutingTableGateway{}
+	case "responsematches":
+		return ResponseMatches{}
+	case "responsematchesinsecure":
+		return ResponseMatchesInsecure{}
+		/***************** packages.go *****************/
+	case "repoexists":
+		return RepoExists{}
+	case "repoexistsuri":
+		return RepoExistsURI{}
+	case "pacmanignore":
+		return PacmanIgnore{}
+	case "installed":
+		return Installed{}
+		/***************** services.go *****************/
+	case "haproxybackends":
+		return HAProxyBackends{}
+	case "nginxstatus":
+		return NginxStatus{}
+	case "phpfpmstatus":
+		return PHPFPMStatus{}
+	case "memcachedstats":
+		return MemcachedStats{}
+	case "mongoreplicahealthy":
+		return MongoReplicaHealthy{}
+	case "cassandranodeup":
+		return CassandraNodeUp{}
+	case "kafkatopic":
+		return KafkaTopic{}
+	case "zookeeperruok":
+		return ZooKeeperRUok{}
+	case "jsonnumericthreshold":
+		return JSONNumericThreshold{}
+	case "jsonarraycontains":
+		return JSONArrayContains{}
+		/***************** systemctl.go *****************/
+	case "systemctlloaded":
+		return SystemctlLoaded{}
+	case "systemctlactive":
+		return SystemctlActive{}
+	case "systemctlsocklistening":
+		return SystemctlSockListening{}
+	case "systemctltimer":
+		return SystemctlTimer{}
+	case "systemctltimerloaded":
+		return SystemctlTimerLoaded{}
+	case "systemctlcapabilitybound":
+		return SystemctlCapabilityBound{}
+	case "systemctluser":
+		return SystemctlUser{}
+		/***************** usage.go *****************/
+	case "memoryusage":
+		return MemoryUsage{}
+	case "swapusage":
+		return SwapUsage{}
+	case "freememory":
+		return FreeMemory{}
+	case "freeswap":
+		return FreeSwap{}
+	case "hugepages":
+		return HugePages{}
+	case "fdexhaustionheadroom":
+		return FDExhaustionHeadroom{}
+	case "cpuusage":
+		return CPUUsage{}
+	case "diskusage":
+		return DiskUsage{}
+	case "inodeusage":
+		return InodeUsage{}
+		/***************** users-and-groups.go *****************/
+	case "groupexists":
+		return GroupExists{}
+	case "useringroup":
+		return UserInGroup{}
+	case "groupid":
+		return GroupID{}
+	case "userexists":
+		return UserExists{}
+	case "userhasuid":
+		return UserHasUID{}
+	case "userhasgid":
+		return UserHasGID{}
+	case "userhasusername":
+		return UserHasUsername{}
+	case "userhashomedir":
+		return UserHasHomeDir{}
+	case "authorizedkey":
+		return AuthorizedKey{}
+	case "authorizedkeynot":
+		return AuthorizedKeyNot{}
+	case "kerberosticket":
+		return KerberosTicket{}
+	}
+	return nil
+}
+
+// ConstructCheck returns a new Check interface compliant object of the type
+// given by its ID, assigned the given parameters
+func ConstructCheck(id string, params []string) (chkutil.Check, error) {
+	chk := lookupCheck(id)
+	if chk == nil {
+		return nil, fmt.Errorf("Invalid check ID: %s", id)
+	}
+	return chk.New(params)
+}
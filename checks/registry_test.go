@@ -0,0 +1,25 @@
+package checks
+
+import (
+	"testing"
+)
+
+func TestConstructCheck(t *testing.T) {
+	t.Parallel()
+	for _, id := range []string{"Directory", "directory", "DIRECTORY"} {
+		chk, err := ConstructCheck(id, []string{"/"})
+		if err != nil {
+			t.Error(err)
+		} else if _, ok := chk.(Directory); !ok {
+			t.Errorf("ConstructCheck(%q) returned a %T, not a Directory", id, chk)
+		} else if code, _, _ := chk.Status(); code != 0 {
+			t.Errorf("ConstructCheck(%q) didn't assign parameters", id)
+		}
+	}
+	bad := [][]string{{"steppenwolf", "/"}, {"", "/"}, {"directory"}}
+	for _, spec := range bad {
+		if _, err := ConstructCheck(spec[0], spec[1:]); err == nil {
+			t.Errorf("ConstructCheck succeeded on %v", spec)
+		}
+	}
+}
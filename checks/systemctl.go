@@ -1,11 +1,15 @@
 package checks
 
 import (
+	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/systemdstatus"
 	"github.com/zeldal/distributive/tabular"
+	"io/ioutil"
 	"os"
+	"os/user"
+	"sort"
 	"strings"
 )
 
@@ -221,3 +225,147 @@ func (chk SystemctlUnitFileStatus) Status() (int, string, error) {
 	msg := "Unit didn't have status"
 	return errutil.GenericError(msg, chk.status, []string{actualStatus})
 }
+
+// capabilitySet normalizes a list of capabilities, separated by spaces or
+// commas, into sorted upper case names with the CAP_ prefix, like those in
+// capabilities(7)
+func capabilitySet(str string) (caps []string) {
+	split := func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }
+	for _, capability := range strings.FieldsFunc(str, split) {
+		capability = strings.ToUpper(capability)
+		if !strings.HasPrefix(capability, "CAP_") {
+			capability = "CAP_" + capability
+		}
+		if !tabular.StrIn(capability, caps) {
+			caps = append(caps, capability)
+		}
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+/*
+#### SystemctlCapabilityBound
+Description: Is this unit's CapabilityBoundingSet exactly this set of
+capabilities?
+Parameters:
+  - Unit (string): Name of systemd unit
+  - Capabilities (string): Space or comma separated capabilities, or "" for none
+Example parameters:
+  - "nginx.service", "sshd.service"
+  - "CAP_NET_BIND_SERVICE", "cap_chown,cap_setuid,cap_setgid", ""
+*/
+
+type SystemctlCapabilityBound struct {
+	unit string
+	caps []string
+}
+
+func (chk SystemctlCapabilityBound) ID() string { return "SystemctlCapabilityBound" }
+
+func (chk SystemctlCapabilityBound) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	for _, capability := range capabilitySet(params[1]) {
+		if capability == "CAP_" || strings.ContainsAny(capability, "~=+-") {
+			return chk, errutil.ParameterTypeError{params[1], "capabilities"}
+		}
+	}
+	chk.unit = params[0]
+	chk.caps = capabilitySet(params[1])
+	return chk, nil
+}
+
+func (chk SystemctlCapabilityBound) Status() (int, string, error) {
+	value, err := systemdstatus.UnitProperty(chk.unit, "CapabilityBoundingSet")
+	if err != nil {
+		return 1, "", err
+	}
+	actual := capabilitySet(value)
+	if strings.Join(actual, " ") == strings.Join(chk.caps, " ") {
+		return errutil.Success()
+	}
+	msg := "Unit had an unexpected CapabilityBoundingSet: " + chk.unit
+	return errutil.GenericError(msg, chk.caps, actual)
+}
+
+// effectiveUID returns the effective user ID of a process, as reported in the
+// Uid line of /proc/<pid>/status (real, effective, saved, filesystem)
+func effectiveUID(pid string) (string, error) {
+	data, err := ioutil.ReadFile("/proc/" + pid + "/status")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "Uid:" {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("No Uid line in /proc/%s/status", pid)
+}
+
+/*
+#### SystemctlUser
+Description: Is this unit configured to run as this user, and is its main
+process actually running as them?
+Parameters:
+  - Unit (string): Name of systemd unit
+  - User (string): Expected username or UID
+Example parameters:
+  - "nginx.service", "postgresql.service"
+  - "www-data", "postgres", "1000"
+*/
+
+type SystemctlUser struct{ unit, user string }
+
+func (chk SystemctlUser) ID() string { return "SystemctlUser" }
+
+func (chk SystemctlUser) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if params[1] == "" {
+		return chk, errutil.ParameterTypeError{params[1], "user"}
+	}
+	chk.unit = params[0]
+	chk.user = params[1]
+	return chk, nil
+}
+
+func (chk SystemctlUser) Status() (int, string, error) {
+	configured, err := systemdstatus.UnitProperty(chk.unit, "User")
+	if err != nil {
+		return 1, "", err
+	} else if configured == "" {
+		configured = "root" // systemd's default
+	}
+	pid, err := systemdstatus.UnitProperty(chk.unit, "MainPID")
+	if err != nil {
+		return 1, "", err
+	} else if pid == "" || pid == "0" {
+		return 1, "Unit has no main process to check the user of: " + chk.unit, nil
+	}
+	uid, err := effectiveUID(pid)
+	if err != nil {
+		return 1, "", err
+	}
+	effective := uid
+	if usr, err := user.LookupId(uid); err == nil {
+		effective = usr.Username
+	}
+	// matches asks: does this username (or its UID) match the expected user?
+	matches := func(name string) bool {
+		if name == chk.user {
+			return true
+		}
+		usr, err := user.Lookup(name)
+		return err == nil && usr.Uid == chk.user
+	}
+	if matches(configured) && (effective == chk.user || uid == chk.user) {
+		return errutil.Success()
+	}
+	msg := "Unit " + chk.unit + " wasn't running as the expected user"
+	actual := []string{"configured: " + configured, "effective: " + effective}
+	return errutil.GenericError(msg, chk.user, actual)
+}
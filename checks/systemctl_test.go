@@ -1,6 +1,8 @@
 package checks
 
 import (
+	"fmt"
+	"os"
 	"testing"
 )
 
@@ -50,3 +52,54 @@ func TestSystemctlUnitFileStatus(t *testing.T) {
 	testParameters(validInputs, notLengthTwo, SystemctlUnitFileStatus{}, t)
 	testCheck(goodEggs, validInputs, SystemctlUnitFileStatus{}, t)
 }
+
+func TestCapabilitySet(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"cap_net_bind_service cap_chown", "CAP_CHOWN,CAP_NET_BIND_SERVICE",
+		"net_bind_service, chown chown",
+	}
+	for _, input := range inputs {
+		actual := fmt.Sprint(capabilitySet(input))
+		if actual != "[CAP_CHOWN CAP_NET_BIND_SERVICE]" {
+			t.Errorf("Misparsed capabilities %q: %v", input, actual)
+		}
+	}
+	if caps := capabilitySet(""); len(caps) != 0 {
+		t.Errorf("Expected no capabilities, got %v", caps)
+	}
+}
+
+func TestSystemctlCapabilityBound(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"nginx.service", "CAP_NET_BIND_SERVICE"}, {"sshd.service", ""},
+		{"foo.service", "cap_chown,cap_setuid cap_setgid"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"foo.service", "CAP_"}, {"foo.service", "~CAP_CHOWN=ep"},
+	}...)
+	testParameters(validInputs, invalidInputs, SystemctlCapabilityBound{}, t)
+	testCheck([][]string{}, appendParameter(names, "CAP_CHOWN"), SystemctlCapabilityBound{}, t)
+}
+
+func TestEffectiveUID(t *testing.T) {
+	t.Parallel()
+	uid, err := effectiveUID(fmt.Sprint(os.Getpid()))
+	if err != nil {
+		t.Error(err)
+	} else if uid != fmt.Sprint(os.Geteuid()) {
+		t.Errorf("Expected effective UID %d, got %s", os.Geteuid(), uid)
+	}
+	if _, err := effectiveUID("lkjashldfb"); err == nil {
+		t.Error("Expected an error for a nonexistent process")
+	}
+}
+
+func TestSystemctlUser(t *testing.T) {
+	t.Parallel()
+	validInputs := appendParameter(names, "www-data")
+	invalidInputs := append(notLengthTwo, []string{"nginx.service", ""})
+	testParameters(validInputs, invalidInputs, SystemctlUser{}, t)
+	testCheck([][]string{}, validInputs, SystemctlUser{}, t)
+}
@@ -181,6 +181,120 @@ func (chk FreeSwap) Status() (int, string, error) {
 	return freeMemOrSwap(chk.amount, "swap")
 }
 
+/*
+#### HugePages
+Description: Does the number of total (or free) hugepages satisfy this
+comparison?
+Parameters:
+  - Which (string): total | free
+  - Operator (string): < | <= | == | != | >= | >
+  - Count (positive int): Number to compare against
+Example parameters:
+  - total, free
+  - ">=", "=="
+  - 512, 1024
+Dependencies:
+  - /proc/meminfo
+*/
+
+type HugePages struct {
+	which, operator string
+	count           uint64
+}
+
+func (chk HugePages) ID() string { return "HugePages" }
+
+func (chk HugePages) New(params []string) (chkutil.Check, error) {
+	if len(params) != 3 {
+		return chk, errutil.ParameterLengthError{3, params}
+	}
+	which := strings.ToLower(params[0])
+	if which != "total" && which != "free" {
+		return chk, errutil.ParameterTypeError{params[0], "total | free"}
+	} else if !chkutil.ValidOperator(params[1]) {
+		return chk, errutil.ParameterTypeError{params[1], "operator"}
+	}
+	count, err := strconv.ParseUint(params[2], 10, 64)
+	if err != nil {
+		return chk, errutil.ParameterTypeError{params[2], "positive int"}
+	}
+	chk.which = which
+	chk.operator = params[1]
+	chk.count = count
+	return chk, nil
+}
+
+func (chk HugePages) Status() (int, string, error) {
+	info, err := memstatus.MemInfo()
+	if err != nil {
+		return 1, "", err
+	}
+	total, free := info["HugePages_Total"], info["HugePages_Free"]
+	actual := total
+	if chk.which == "free" {
+		actual = free
+	}
+	if chkutil.Compare(chk.operator, float64(actual), float64(chk.count)) {
+		return errutil.Success()
+	}
+	msg := "Number of " + chk.which + " hugepages was " + fmt.Sprint(actual)
+	counts := []string{
+		"HugePages_Total: " + fmt.Sprint(total),
+		"HugePages_Free: " + fmt.Sprint(free),
+	}
+	return errutil.GenericError(msg, chk.operator+" "+fmt.Sprint(chk.count), counts)
+}
+
+/*
+#### FDExhaustionHeadroom
+Description: Is at least this percentage of the system-wide maximum number of
+file handles still available?
+Parameters:
+  - Percent (float percentage): Minimum acceptable percentage available
+Example parameters:
+  - 10%, 25%, 50
+Dependencies:
+  - /proc/sys/fs/file-nr
+*/
+
+type FDExhaustionHeadroom struct{ minPercentFree float64 }
+
+func (chk FDExhaustionHeadroom) ID() string { return "FDExhaustionHeadroom" }
+
+func (chk FDExhaustionHeadroom) New(params []string) (chkutil.Check, error) {
+	if len(params) != 1 {
+		return chk, errutil.ParameterLengthError{1, params}
+	}
+	per, err := strconv.ParseFloat(strings.TrimSuffix(params[0], "%"), 64)
+	if err != nil || per < 0 || per > 100 {
+		return chk, errutil.ParameterTypeError{params[0], "percentage"}
+	}
+	chk.minPercentFree = per
+	return chk, nil
+}
+
+func (chk FDExhaustionHeadroom) Status() (int, string, error) {
+	allocated, unused, max, err := fsstatus.FileHandles()
+	if err != nil {
+		return 1, "", err
+	}
+	inUse := allocated - unused
+	var percentFree float64
+	if max > inUse {
+		percentFree = float64(max-inUse) / float64(max) * 100
+	}
+	if percentFree >= chk.minPercentFree {
+		return errutil.Success()
+	}
+	msg := "Too few file handles available: " + fmt.Sprintf("%.2f%%", percentFree)
+	figures := []string{
+		"allocated: " + fmt.Sprint(allocated),
+		"unused: " + fmt.Sprint(unused),
+		"max: " + fmt.Sprint(max),
+	}
+	return errutil.GenericError(msg, fmt.Sprint(chk.minPercentFree)+"%", figures)
+}
+
 // getCPUSample helps CPUUsage do its thing. Taken from a stackoverflow:
 // http://stackoverflow.com/questions/11356330/getting-cpu-usage-with-golang
 func getCPUSample() (idle, total uint64) {
@@ -56,6 +56,37 @@ func TestFreeSwap(t *testing.T) {
 	testFreeMemoryOrSwap(t, FreeSwap{})
 }
 
+func TestHugePages(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{
+		{"total", ">=", "512"}, {"free", "==", "0"}, {"TOTAL", "!=", "1"},
+	}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"used", ">=", "0"}, {"total", "=>", "0"}, {"total", ">=", "-1"},
+		{"free", "<", "many"},
+	}...)
+	goodEggs := [][]string{
+		{"total", ">=", "0"}, {"free", "<=", "1000000000"},
+	}
+	badEggs := [][]string{
+		{"total", "<", "0"}, {"free", ">", "1000000000"},
+	}
+	testParameters(validInputs, invalidInputs, HugePages{}, t)
+	testCheck(goodEggs, badEggs, HugePages{}, t)
+}
+
+func TestFDExhaustionHeadroom(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"10%"}, {"25"}, {"0.5%"}, {"100%"}}
+	invalidInputs := append(notLengthOne, [][]string{
+		{"-1%"}, {"101%"}, {"lots"},
+	}...)
+	goodEggs := [][]string{{"0%"}, {"1%"}}
+	badEggs := [][]string{{"100%"}}
+	testParameters(validInputs, invalidInputs, FDExhaustionHeadroom{}, t)
+	testCheck(goodEggs, badEggs, FDExhaustionHeadroom{}, t)
+}
+
 // $1 - path, $2 maxpercent
 func TestDiskUsage(t *testing.T) {
 	t.Parallel()
@@ -1,14 +1,24 @@
 package checks
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/usrstatus"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"os/user"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // validGroupName asks: Is this a valid POSIX+Linux group name?
@@ -411,3 +421,298 @@ func (chk UserHasHomeDir) New(params []string) (chkutil.Check, error) {
 func (chk UserHasHomeDir) Status() (int, string, error) {
 	return genericUserField(chk.usernameOrUID, "HomeDir", chk.expectedHomeDir)
 }
+
+// keyFingerprints returns the fingerprints of a base64-encoded public key, in
+// the formats that ssh-keygen -l uses: SHA256:..., MD5:aa:bb:..., and the
+// legacy aa:bb:... form
+func keyFingerprints(encoded string) []string {
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+	sha := sha256.Sum256(blob)
+	sum := md5.Sum(blob)
+	var hexBytes []string
+	for _, b := range sum {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02x", b))
+	}
+	legacy := strings.Join(hexBytes, ":")
+	return []string{
+		"SHA256:" + base64.RawStdEncoding.EncodeToString(sha[:]),
+		"MD5:" + legacy,
+		legacy,
+	}
+}
+
+// authorizedKeyMatches reports whether any key in the given authorized_keys
+// file has this fingerprint or contains this substring, along with the number
+// of keys in the file.
+func authorizedKeyMatches(path string, key string) (found bool, count int, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, 0, err
+	}
+	keyTypeRe := regexp.MustCompile(`^(ssh|ecdsa|sk)-`)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		count++
+		if strings.Contains(line, key) {
+			found = true
+			continue
+		}
+		// the key itself follows its type, possibly after some options
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields)-1; i++ {
+			if keyTypeRe.MatchString(fields[i]) {
+				for _, fingerprint := range keyFingerprints(fields[i+1]) {
+					found = found || fingerprint == key
+				}
+				break
+			}
+		}
+	}
+	return found, count, nil
+}
+
+// authorizedKeyCheck is an abstraction of AuthorizedKey and AuthorizedKeyNot,
+// which look for a key in ~user/.ssh/authorized_keys. A missing file counts as
+// a file without any keys.
+func authorizedKeyCheck(usernameOrUID string, key string, negate bool) (int, string, error) {
+	usr, err := lookupUser(usernameOrUID)
+	if err != nil {
+		return 1, "User does not exist: " + usernameOrUID, nil
+	}
+	path := filepath.Join(usr.HomeDir, ".ssh", "authorized_keys")
+	found, count, err := authorizedKeyMatches(path, key)
+	if err != nil && !os.IsNotExist(err) {
+		return 1, "", err
+	}
+	switch {
+	case found && negate:
+		return 1, "Revoked key is still authorized: " + key + "\n\tFile: " + path, nil
+	case found || negate:
+		return errutil.Success()
+	}
+	msg := "Key not authorized for user " + usernameOrUID
+	msg += "\n\tKey: " + key
+	msg += "\n\tFile: " + path
+	msg += "\n\tAuthorized keys: " + fmt.Sprint(count)
+	return 1, msg, nil
+}
+
+/*
+#### AuthorizedKey
+Description: Is this key present in this user's ~/.ssh/authorized_keys?
+Parameters:
+  - Username/UID (username or UID)
+  - Key (string): Fingerprint (as from ssh-keygen -l) or substring of the key
+Example parameters:
+  - lb, root, 0
+  - "SHA256:KqP1pTbJFx7MGmjvz/+j/XFgtPhY5WdwZvy7hbnQgMg", "deploy@ci"
+*/
+
+type AuthorizedKey struct{ usernameOrUID, key string }
+
+func (chk AuthorizedKey) ID() string { return "AuthorizedKey" }
+
+func (chk AuthorizedKey) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if strings.TrimSpace(params[1]) == "" {
+		return chk, errutil.ParameterTypeError{params[1], "key"}
+	}
+	chk.usernameOrUID = params[0]
+	chk.key = strings.TrimSpace(params[1])
+	return chk, nil
+}
+
+func (chk AuthorizedKey) Status() (int, string, error) {
+	return authorizedKeyCheck(chk.usernameOrUID, chk.key, false)
+}
+
+/*
+#### AuthorizedKeyNot
+Description: Like AuthorizedKey, but passes only if the key is *absent*.
+Useful for ensuring that revoked keys stay revoked.
+Parameters:
+  - Username/UID (username or UID)
+  - Key (string): Fingerprint (as from ssh-keygen -l) or substring of the key
+Example parameters:
+  - lb, root, 0
+  - "MD5:4c:a9:06:bb:86:51:0f:a6:5d:dc:de:06:0b:08:0b:35", "former@employee"
+*/
+
+type AuthorizedKeyNot struct{ usernameOrUID, key string }
+
+func (chk AuthorizedKeyNot) ID() string { return "AuthorizedKeyNot" }
+
+func (chk AuthorizedKeyNot) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	} else if strings.TrimSpace(params[1]) == "" {
+		return chk, errutil.ParameterTypeError{params[1], "key"}
+	}
+	chk.usernameOrUID = params[0]
+	chk.key = strings.TrimSpace(params[1])
+	return chk, nil
+}
+
+func (chk AuthorizedKeyNot) Status() (int, string, error) {
+	return authorizedKeyCheck(chk.usernameOrUID, chk.key, true)
+}
+
+// klistTimeLayouts are the formats klist prints times in: MIT Kerberos's
+// depend on the locale, and Heimdal's look like ctime's, with or without the
+// year. klist is run in the C locale, so slash-separated dates are month
+// first. Day-first ones can't be told apart from those, so they're left out,
+// to be rejected rather than misread.
+var klistTimeLayouts = []string{
+	"01/02/06 15:04:05", "01/02/2006 15:04:05", "2006-01-02 15:04:05",
+	"02.01.2006 15:04:05", "Jan 2 15:04:05 2006", "Jan 2 15:04:05",
+}
+
+// parseKlistTime parses a time printed by klist, in the local time zone. If it
+// has no year, the one that puts it closest to now is used.
+func parseKlistTime(str string, now time.Time) (time.Time, error) {
+	for _, layout := range klistTimeLayouts {
+		t, err := time.ParseInLocation(layout, str, time.Local)
+		if err != nil {
+			continue
+		} else if t.Year() != 0 {
+			return t, nil
+		}
+		var closest time.Time
+		for i, year := range []int{now.Year() - 1, now.Year(), now.Year() + 1} {
+			candidate := time.Date(year, t.Month(), t.Day(), t.Hour(),
+				t.Minute(), t.Second(), 0, time.Local)
+			if i == 0 || absDuration(candidate.Sub(now)) < absDuration(closest.Sub(now)) {
+				closest = candidate
+			}
+		}
+		return closest, nil
+	}
+	return time.Time{}, errors.New("Couldn't parse time from klist: " + str)
+}
+
+// absDuration returns the absolute value of a duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// heimdalExpired is what Heimdal's klist prints instead of the expiry of a
+// ticket that has expired
+const heimdalExpired = ">>>Expired<<<"
+
+// tgtExpiry finds the expiry of the ticket-granting ticket in the output of
+// klist (or klist -A), printed at the time now. If principal isn't empty, only
+// the credentials cache whose default principal it is will be considered.
+// found is false if there was no such ticket. Heimdal doesn't print the
+// expiry of expired tickets, so for those, expiry is the zero time.
+func tgtExpiry(out string, principal string, now time.Time) (expiry time.Time, found bool, err error) {
+	inCache := principal == ""
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "Ticket cache:"), // MIT
+			strings.HasPrefix(line, "Credentials cache:"): // Heimdal
+			inCache = principal == ""
+		case strings.HasPrefix(line, "Default principal:"),
+			strings.HasPrefix(strings.TrimSpace(line), "Principal:"):
+			inCache = inCache || fields[len(fields)-1] == principal
+		case inCache && len(fields) >= 3 &&
+			strings.HasPrefix(fields[len(fields)-1], "krbtgt/"):
+			// the fields are the start time, expiry, and service principal
+			times := fields[:len(fields)-1]
+			if times[len(times)-1] == heimdalExpired {
+				return time.Time{}, true, nil
+			}
+			expiry, err = parseKlistTime(strings.Join(times[len(times)/2:], " "), now)
+			return expiry, err == nil, err
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+/*
+#### KerberosTicket
+Description: Is there a Kerberos ticket-granting ticket that will stay valid
+for at least this long?
+Parameters:
+  - Principal (string): Principal whose credentials cache to check, or "" for
+    the default credentials cache
+  - Validity (time.Duration): Minimum time until the ticket expires
+Example parameters:
+  - "", "alice@EXAMPLE.COM", "host/web01.example.com@EXAMPLE.COM"
+  - 0s, 1h, 8h
+Dependencies:
+  - klist
+*/
+
+type KerberosTicket struct {
+	principal string
+	validity  time.Duration
+}
+
+func (chk KerberosTicket) ID() string { return "KerberosTicket" }
+
+func (chk KerberosTicket) New(params []string) (chkutil.Check, error) {
+	if len(params) != 2 {
+		return chk, errutil.ParameterLengthError{2, params}
+	}
+	validity, err := time.ParseDuration(params[1])
+	if err != nil || validity < 0 {
+		return chk, errutil.ParameterTypeError{params[1], "time.Duration"}
+	}
+	chk.principal = params[0]
+	chk.validity = validity
+	return chk, nil
+}
+
+func (chk KerberosTicket) Status() (int, string, error) {
+	if _, err := exec.LookPath("klist"); err != nil {
+		return 1, "Couldn't find klist to list Kerberos tickets", nil
+	}
+	cmd := exec.Command("klist")
+	if chk.principal != "" {
+		cmd = exec.Command("klist", "-A") // list every credentials cache
+	}
+	// the C locale's dates are unambiguous, see klistTimeLayouts
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(strings.ToLower(string(out)), "not found") ||
+			strings.Contains(string(out), "No credentials cache") {
+			return 1, "No Kerberos credentials cache found", nil
+		}
+		return 1, "", errors.New(err.Error() + ": output: " + string(out))
+	}
+	now := time.Now()
+	expiry, found, err := tgtExpiry(string(out), chk.principal, now)
+	if err != nil {
+		return 1, "", err
+	} else if !found && chk.principal != "" {
+		return 1, "No Kerberos ticket-granting ticket for " + chk.principal, nil
+	} else if !found {
+		return 1, "No Kerberos ticket-granting ticket in the default cache", nil
+	} else if expiry.IsZero() {
+		return errutil.GenericError("Kerberos ticket has expired",
+			"valid for "+chk.validity.String(), []string{"expired"})
+	}
+	remaining := expiry.Sub(now)
+	if remaining >= chk.validity && remaining > 0 {
+		return errutil.Success()
+	}
+	msg := "Kerberos ticket expires too soon"
+	if remaining <= 0 {
+		msg = "Kerberos ticket has expired"
+	}
+	msg += " (at " + expiry.Format(time.RFC3339) + ")"
+	return errutil.GenericError(msg, "valid for "+chk.validity.String(),
+		[]string{"valid for " + remaining.String()})
+}
@@ -2,7 +2,10 @@ package checks
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 )
 
 var validUsernamesOrUIDs = append(append(names, smallInts...), bigIntsUnder100...)
@@ -107,3 +110,167 @@ func TestUserHasHomeDir(t *testing.T) {
 	testParameters(validInputs, notLengthTwo, UserHasHomeDir{}, t)
 	testCheck(goodEggs, badEggs, UserHasHomeDir{}, t)
 }
+
+func TestAuthorizedKeyMatches(t *testing.T) {
+	t.Parallel()
+	file, err := ioutil.TempFile("", "authorized_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	contents := "# comment\n\n"
+	contents += `no-pty,command="echo hi" ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKm5h6+ImEQ05zWGKi2MBONSF7V+RwlYCghJ/PwNRYsG test@example`
+	contents += "\nssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ other@example\n"
+	file.WriteString(contents)
+	file.Close()
+	inputs := []string{
+		"SHA256:KqP1pTbJFx7MGmjvz/+j/XFgtPhY5WdwZvy7hbnQgMg",
+		"MD5:4c:a9:06:bb:86:51:0f:a6:5d:dc:de:06:0b:08:0b:35",
+		"4c:a9:06:bb:86:51:0f:a6:5d:dc:de:06:0b:08:0b:35",
+		"other@example", "IKm5h6+ImEQ05zWGKi2MBONSF7V",
+		"SHA256:KqP1pTbJFx7MGmjvz", "deploy@ci", "comment",
+	}
+	outputs := []bool{true, true, true, true, true, false, false, false}
+	for i := range inputs {
+		found, count, err := authorizedKeyMatches(file.Name(), inputs[i])
+		if err != nil {
+			t.Error(err.Error())
+		} else if found != outputs[i] || count != 2 {
+			msg := "authorizedKeyMatches gave unexpected results"
+			msg += "\n\tKey: " + inputs[i]
+			msg += "\n\tExpected: " + fmt.Sprint(outputs[i], 2)
+			msg += "\n\tActual: " + fmt.Sprint(found, count)
+			t.Error(msg)
+		}
+	}
+}
+
+func TestAuthorizedKey(t *testing.T) {
+	t.Parallel()
+	validInputs := appendParameter(names, "SHA256:KqP1pTbJFx7MGmjvz")
+	invalidInputs := append(notLengthTwo, appendParameter(names, " ")...)
+	badEggs := append(appendParameter(names, "deploy@ci"),
+		[]string{"root", "SHA256:KqP1pTbJFx7MGmjvz/+j/XFgtPhY5WdwZvy7hbnQgMg"})
+	testParameters(validInputs, invalidInputs, AuthorizedKey{}, t)
+	testCheck([][]string{}, badEggs, AuthorizedKey{}, t)
+}
+
+func TestAuthorizedKeyNot(t *testing.T) {
+	t.Parallel()
+	validInputs := appendParameter(names, "SHA256:KqP1pTbJFx7MGmjvz")
+	invalidInputs := append(notLengthTwo, appendParameter(names, "")...)
+	goodEggs := [][]string{
+		{"root", "SHA256:KqP1pTbJFx7MGmjvz/+j/XFgtPhY5WdwZvy7hbnQgMg"},
+	}
+	badEggs := appendParameter(names, "deploy@ci")
+	testParameters(validInputs, invalidInputs, AuthorizedKeyNot{}, t)
+	testCheck(goodEggs, badEggs, AuthorizedKeyNot{}, t)
+}
+
+func TestTGTExpiry(t *testing.T) {
+	t.Parallel()
+	mit := `Ticket cache: KCM:1000:12345
+Default principal: bob@EXAMPLE.COM
+
+Valid starting       Expires              Service principal
+10/15/2026 08:00:00  10/15/2026 18:00:00  HTTP/www.example.com@EXAMPLE.COM
+10/15/2026 09:00:00  10/15/2026 19:00:00  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+	renew until 10/22/2026 09:00:00
+
+Ticket cache: FILE:/tmp/krb5cc_1000
+Default principal: alice@EXAMPLE.COM
+
+Valid starting     Expires            Service principal
+10/16/26 10:30:00  10/16/26 20:30:00  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+`
+	// laid out as Heimdal's klist prints them: older versions leave out the
+	// year, and expired tickets have no expiry
+	heimdal := `Credentials cache: FILE:/tmp/krb5cc_1000
+        Principal: carol@EXAMPLE.COM
+
+  Issued           Expires          Principal
+Oct 14 09:00:00  Oct 14 19:00:00  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+
+Credentials cache: API:501:3
+        Principal: dave@EXAMPLE.COM
+
+  Issued                Expires               Principal
+Oct 15 08:00:00 2026  Oct 15 18:00:00 2026  krbtgt/EXAMPLE.COM@EXAMPLE.COM
+
+Credentials cache: FILE:/tmp/krb5cc_1001
+        Principal: erin@EXAMPLE.COM
+
+  Issued                Expires               Principal
+Oct 13 08:00:00 2026  >>>Expired<<<         HTTP/www.example.com@EXAMPLE.COM
+Oct 13 08:00:00 2026  >>>Expired<<<         krbtgt/EXAMPLE.COM@EXAMPLE.COM
+`
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		out, principal, expiry string
+		found                  bool
+	}{
+		{mit, "", "2026-10-15 19:00:00", true},
+		{mit, "bob@EXAMPLE.COM", "2026-10-15 19:00:00", true},
+		{mit, "alice@EXAMPLE.COM", "2026-10-16 20:30:00", true},
+		{mit, "eve@EXAMPLE.COM", "", false},
+		{heimdal, "carol@EXAMPLE.COM", "2026-10-14 19:00:00", true},
+		{heimdal, "dave@EXAMPLE.COM", "2026-10-15 18:00:00", true},
+		{heimdal, "erin@EXAMPLE.COM", "0001-01-01 00:00:00", true},
+		{"Ticket cache: FILE:/tmp/krb5cc_0\nDefault principal: a@B\n", "", "", false},
+		// a principal without any times
+		{"Ticket cache: FILE:/tmp/krb5cc_0\nkrbtgt/EXAMPLE.COM@EXAMPLE.COM\n", "", "", false},
+	}
+	for _, test := range tests {
+		expiry, found, err := tgtExpiry(test.out, test.principal, now)
+		if err != nil {
+			t.Error(err)
+		} else if found != test.found {
+			t.Errorf("Expected found == %v for principal %q", test.found, test.principal)
+		} else if found && expiry.Format("2006-01-02 15:04:05") != test.expiry {
+			t.Errorf("Expected expiry %v for principal %q, got %v",
+				test.expiry, test.principal, expiry)
+		}
+	}
+}
+
+func TestParseKlistTime(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.Local)
+	inputs := []string{
+		"10/12/26 09:00:00", "10/12/2026 09:00:00", "2026-10-12 09:00:00",
+		"12.10.2026 09:00:00", "Oct 12 09:00:00 2026", "Oct 12 09:00:00",
+	}
+	for _, input := range inputs {
+		actual, err := parseKlistTime(input, now)
+		if err != nil {
+			t.Error(err)
+		} else if actual.Format("2006-01-02 15:04:05") != "2026-10-12 09:00:00" {
+			t.Errorf("parseKlistTime(%q) = %v", input, actual)
+		}
+	}
+	// times without a year are put in the year closest to now
+	newYear := time.Date(2027, time.January, 1, 0, 30, 0, 0, time.Local)
+	if actual, err := parseKlistTime("Dec 31 20:00:00", newYear); err != nil {
+		t.Error(err)
+	} else if actual.Year() != 2026 {
+		t.Errorf("parseKlistTime put New Year's Eve in %d", actual.Year())
+	}
+	// day-first dates aren't guessed at
+	for _, input := range []string{"13/10/26 09:00:00", "tomorrow"} {
+		if actual, err := parseKlistTime(input, now); err == nil {
+			t.Errorf("parseKlistTime(%q) = %v, expected an error", input, actual)
+		}
+	}
+}
+
+func TestKerberosTicket(t *testing.T) {
+	t.Parallel()
+	validInputs := [][]string{{"", "0s"}, {"alice@EXAMPLE.COM", "8h"}}
+	invalidInputs := append(notLengthTwo, [][]string{
+		{"", "8"}, {"", "-1h"}, {"alice@EXAMPLE.COM", "eight hours"},
+	}...)
+	// there are no Kerberos tickets in the test environment
+	badEggs := [][]string{{"", "0s"}, {"alice@EXAMPLE.COM", "1h"}}
+	testParameters(validInputs, invalidInputs, KerberosTicket{}, t)
+	testCheck([][]string{}, badEggs, KerberosTicket{}, t)
+}
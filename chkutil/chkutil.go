@@ -3,6 +3,7 @@ package chkutil
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"github.com/zeldal/distributive/errutil"
 	"github.com/zeldal/distributive/tabular"
@@ -13,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Check is a unified interface for health checks, it defines only the minimal
@@ -104,6 +106,47 @@ func SeparateByteUnits(str string) (int, string, error) {
 	return scalar, unit, nil
 }
 
+// ParseBytes converts a string with byte units (see SeparateByteUnits) into
+// a number of bytes, using powers of 1024, e.g. 2kb -> 2048
+func ParseBytes(str string) (uint64, error) {
+	amount, unit, err := SeparateByteUnits(str)
+	if err != nil {
+		return 0, err
+	}
+	multipliers := map[string]uint64{
+		"b": 1, "kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30, "tb": 1 << 40,
+	}
+	return uint64(amount) * multipliers[unit], nil
+}
+
+// ComparisonOperators are the operators that checks accept when comparing some
+// measured quantity against a user supplied one, see Compare.
+var ComparisonOperators = []string{"<", "<=", "==", "!=", ">=", ">"}
+
+// ValidOperator asks: Is this one of the ComparisonOperators?
+func ValidOperator(op string) bool { return tabular.StrIn(op, ComparisonOperators) }
+
+// Compare reports whether the statement "actual op expected" is true, e.g.
+// Compare(">=", 3, 2) == true. Unknown operators always yield false, so
+// parameters should be checked with ValidOperator beforehand.
+func Compare(op string, actual float64, expected float64) bool {
+	switch op {
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	case "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case ">=":
+		return actual >= expected
+	case ">":
+		return actual > expected
+	}
+	return false
+}
+
 // SubmatchMap returns a map of submatch names to their captures, if any.
 // If no matches are found, it returns an empty dict.
 // Submatch names are specified using (?P<name>[matchme])
@@ -150,6 +193,68 @@ func BytesToFile(data []byte, path string) {
 	}
 }
 
+// GetURL gets the response body from urlstr, reporting any errors instead of
+// exiting. Responses with a non-2xx status code are also treated as errors.
+func GetURL(urlstr string, secure bool) ([]byte, error) {
+	// create http client
+	transport := &http.Transport{}
+	if !secure {
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	resp, err := client.Get(urlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return body, err
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return body, errors.New("Bad response from " + urlstr + ": " + resp.Status)
+	}
+	return body, nil
+}
+
+// JSONPath decodes the JSON document in data and returns the value at the
+// dotted path within it, like "cluster.nodes.0.name", where array elements are
+// addressed by their index. An empty path refers to the whole document.
+// Numbers are returned as json.Number, so that they keep their precision.
+func JSONPath(data []byte, path string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.New("Couldn't decode JSON: " + err.Error())
+	}
+	if path == "" {
+		return value, nil
+	}
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		current := strings.Join(keys[:i+1], ".")
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			child, ok := typed[key]
+			if !ok {
+				return nil, errors.New("No such key in JSON: " + current)
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, errors.New("No such array index in JSON: " + current)
+			}
+			value = typed[index]
+		default:
+			return nil, errors.New("Can't index into JSON scalar at: " + current)
+		}
+	}
+	return value, nil
+}
+
 // URLToBytes gets the response from urlstr and returns it as a byte string
 // TODO wait on a goroutine w/ timeout, instead of blocking main thread
 func URLToBytes(urlstr string, secure bool) []byte {
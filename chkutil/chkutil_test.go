@@ -2,6 +2,8 @@ package chkutil
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os/exec"
 	"strings"
 	"testing"
@@ -58,6 +60,60 @@ func TestSeparateByteUnits(t *testing.T) {
 	}
 }
 
+func TestParseBytes(t *testing.T) {
+	t.Parallel()
+	inputs := []string{"10b", "2kb", "3MiB", "1gb", "1TB"}
+	outputs := []uint64{10, 2048, 3 << 20, 1 << 30, 1 << 40}
+	for i := range inputs {
+		actual, err := ParseBytes(inputs[i])
+		if err != nil || actual != outputs[i] {
+			msg := "ParseBytes didn't properly convert to bytes"
+			msg += "\n\tString: " + inputs[i]
+			msg += "\n\tExpected: " + fmt.Sprint(outputs[i])
+			msg += "\n\tActual: " + fmt.Sprint(actual)
+			msg += "\n\tError: " + fmt.Sprint(err)
+			t.Error(msg)
+		}
+	}
+	if _, err := ParseBytes("lots"); err == nil {
+		t.Error("ParseBytes didn't return an error for a string without units")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	// each operator is tried on (1, 2), (2, 2), and (3, 2)
+	expected := map[string][]bool{
+		"<":  {true, false, false},
+		"<=": {true, true, false},
+		"==": {false, true, false},
+		"!=": {true, false, true},
+		">=": {false, true, true},
+		">":  {false, false, true},
+	}
+	for op, results := range expected {
+		if !ValidOperator(op) {
+			t.Error("ValidOperator rejected a valid operator: " + op)
+		}
+		for i, result := range results {
+			actual := float64(i + 1)
+			if Compare(op, actual, 2) != result {
+				msg := "Compare gave an unexpected result"
+				msg += "\n\tStatement: " + fmt.Sprint(actual, " ", op, " 2")
+				msg += "\n\tExpected: " + fmt.Sprint(result)
+				t.Error(msg)
+			}
+		}
+	}
+	for _, op := range []string{"", "=", "=<", "lt", "<<"} {
+		if ValidOperator(op) {
+			t.Error("ValidOperator accepted an invalid operator: " + op)
+		} else if Compare(op, 1, 1) {
+			t.Error("Compare returned true for invalid operator: " + op)
+		}
+	}
+}
+
 // TODO
 func TestSubmatchMap(t *testing.T) {
 	t.Parallel()
@@ -130,7 +186,61 @@ func TestURLToBytes(t *testing.T) {
 	// TODO
 }
 
+func TestGetURL(t *testing.T) {
+	t.Parallel()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "test")
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	if body, err := GetURL(server.URL, true); err != nil || string(body) != "test" {
+		t.Errorf("GetURL(%v) returned %q, %v", server.URL, body, err)
+	}
+	if _, err := GetURL(server.URL+"/missing", true); err == nil {
+		t.Error("GetURL didn't return an error on a 404")
+	}
+	if _, err := GetURL("http://127.0.0.1:0", true); err == nil {
+		t.Error("GetURL didn't return an error on an unreachable host")
+	}
+}
+
 func TestGetFileWithExtension(t *testing.T) {
 	t.Parallel()
 	// TODO
 }
+
+func TestJSONPath(t *testing.T) {
+	t.Parallel()
+	data := []byte(`{"status": "ok", "gauges": {"queue": {"depth": 42}},
+		"nodes": [{"name": "a", "up": true}, {"name": "b", "up": false}],
+		"big": 12345678901234567890}`)
+	good := map[string]string{
+		"status": "ok", "gauges.queue.depth": "42", "nodes.1.name": "b",
+		"nodes.0.up": "true", "big": "12345678901234567890",
+	}
+	for path, expected := range good {
+		value, err := JSONPath(data, path)
+		if err != nil {
+			t.Errorf("Unexpected error for path %q: %v", path, err)
+		} else if fmt.Sprint(value) != expected {
+			t.Errorf("Expected %q at path %q, got %v", expected, path, value)
+		}
+	}
+	if value, err := JSONPath(data, ""); err != nil || value == nil {
+		t.Errorf("Expected the whole document for an empty path, got %v, %v", value, err)
+	}
+	bad := []string{"missing", "gauges.queue.width", "nodes.2", "nodes.-1",
+		"nodes.name", "status.length"}
+	for _, path := range bad {
+		if value, err := JSONPath(data, path); err == nil {
+			t.Errorf("Expected an error for path %q, got %v", path, value)
+		}
+	}
+	if _, err := JSONPath([]byte("{not json"), "status"); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
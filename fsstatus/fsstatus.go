@@ -3,19 +3,26 @@
 package fsstatus
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/zeldal/distributive/tabular"
 	"golang.org/x/crypto/sha3"
 	"hash"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // IsFile checks to see if there's a regular ol' file at path.
@@ -99,6 +106,287 @@ func FileHasPermissions(expectedPerms string, path string) (bool, error) {
 	return (actualMode == expectedPerms), nil
 }
 
+// FirstDifference streams the files at the two paths and returns the offset
+// of the first byte at which they differ, or -1 if their contents are
+// identical. If one file is a prefix of the other, the offset is the length of
+// the shorter one.
+func FirstDifference(path1, path2 string) (offset int64, err error) {
+	f1, err := os.Open(path1)
+	if err != nil {
+		return -1, err
+	}
+	defer f1.Close()
+	f2, err := os.Open(path2)
+	if err != nil {
+		return -1, err
+	}
+	defer f2.Close()
+	// finished reports whether an error from io.ReadFull just means EOF
+	finished := func(err error) bool {
+		return err == io.EOF || err == io.ErrUnexpectedEOF
+	}
+	buf1 := make([]byte, 32*1024)
+	buf2 := make([]byte, 32*1024)
+	for {
+		n1, err1 := io.ReadFull(f1, buf1)
+		if err1 != nil && !finished(err1) {
+			return -1, err1
+		}
+		n2, err2 := io.ReadFull(f2, buf2)
+		if err2 != nil && !finished(err2) {
+			return -1, err2
+		}
+		for i := 0; i < n1 && i < n2; i++ {
+			if buf1[i] != buf2[i] {
+				return offset + int64(i), nil
+			}
+		}
+		if n1 != n2 {
+			if n1 < n2 {
+				return offset + int64(n1), nil
+			}
+			return offset + int64(n2), nil
+		}
+		// equal short reads mean that both files ended here
+		if err1 != nil {
+			return -1, nil
+		}
+		offset += int64(n1)
+	}
+}
+
+// HeadLines returns the first n lines of the file at path, without reading
+// any further than it needs to
+func HeadLines(path string, n int) (lines []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for len(lines) < n && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// TailLines returns the last n lines of the file at path. Regular files are
+// read backwards from their end, so only the tail is read. Files that don't
+// report their size (like those in /proc) are read from the start.
+func TailLines(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// lastLines splits data into lines and returns the last n of them
+	lastLines := func(data []byte) []string {
+		str := strings.TrimSuffix(string(data), "\n")
+		if str == "" || n < 1 {
+			return []string{}
+		}
+		lines := strings.Split(str, "\n")
+		if len(lines) > n {
+			return lines[len(lines)-n:]
+		}
+		return lines
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return lastLines(data), nil
+	}
+	// read chunks from the end until we have more than n newlines (the last
+	// one might just end the final line), or reach the start of the file
+	const chunkSize = 4096
+	var tail []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(tail, []byte("\n")) <= n {
+		size := int64(chunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+	}
+	return lastLines(tail), nil
+}
+
+// CountString returns the number of non-overlapping occurrences of substr in
+// the file at path. The file is streamed in chunks, carrying over the end of
+// each so that occurrences spanning two chunks are still counted once.
+func CountString(path string, substr string) (count uint64, err error) {
+	if substr == "" {
+		return 0, errors.New("Can't count occurrences of the empty string")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	chunk := make([]byte, 32*1024)
+	carry := ""
+	for {
+		n, err := file.Read(chunk)
+		buf := carry + string(chunk[:n])
+		pos := 0
+		for {
+			i := strings.Index(buf[pos:], substr)
+			if i < 0 {
+				break
+			}
+			count++
+			pos += i + len(substr)
+		}
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return 0, err
+		}
+		// only the last len(substr)-1 bytes after the last match could start
+		// an occurrence that continues into the next chunk
+		if keep := len(buf) - len(substr) + 1; keep > pos {
+			pos = keep
+		}
+		carry = buf[pos:]
+	}
+}
+
+// DirectorySize returns the total size in bytes of all the regular files in the
+// tree rooted at root. Symlinks are skipped unless follow is set, in which case
+// the files and directories they point to are counted as well. Either way, each
+// file or directory is only visited once, so hard links and symlinks can't
+// cause double counting or loops. Entries under root that can't be read (e.g.
+// for lack of permissions) aren't counted, and are returned as skipped.
+func DirectorySize(root string, follow bool) (size uint64, skipped []string, err error) {
+	type inode struct{ dev, ino uint64 }
+	seen := make(map[inode]bool)
+	var walk func(root string) error
+	walkFn := func(path string, finfo os.FileInfo, err error) error {
+		if err != nil && path == root {
+			return err
+		} else if err != nil {
+			skipped = append(skipped, path)
+			return nil
+		}
+		if finfo.Mode()&os.ModeSymlink != 0 {
+			if !follow {
+				return nil
+			}
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil { // dangling link
+				return nil
+			}
+			return walk(target)
+		}
+		if stat, ok := finfo.Sys().(*syscall.Stat_t); ok {
+			key := inode{uint64(stat.Dev), uint64(stat.Ino)}
+			if seen[key] {
+				if finfo.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			seen[key] = true
+		}
+		if finfo.Mode().IsRegular() {
+			size += uint64(finfo.Size())
+		}
+		return nil
+	}
+	walk = func(root string) error { return filepath.Walk(root, walkFn) }
+	err = walk(root)
+	return size, skipped, err
+}
+
+// Mount is one entry of /proc/mounts
+type Mount struct {
+	Device, MountPoint, Type string
+	Options                  []string
+}
+
+// HasOption reports whether this mount has the given option. Options without
+// a value (like "size") also match options that have one (like "size=10k").
+func (mount Mount) HasOption(option string) bool {
+	for _, opt := range mount.Options {
+		if opt == option {
+			return true
+		} else if !strings.Contains(option, "=") &&
+			strings.SplitN(opt, "=", 2)[0] == option {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeMountField undoes the octal escaping of whitespace and backslashes
+// in /proc/mounts, e.g. "/mnt/my\040disk" -> "/mnt/my disk"
+func unescapeMountField(field string) string {
+	for _, escape := range []string{`\040`, `\011`, `\012`, `\134`} {
+		char, _ := strconv.ParseUint(escape[1:], 8, 8)
+		field = strings.Replace(field, escape, string(rune(char)), -1)
+	}
+	return field
+}
+
+// parseMounts parses data in the format of /proc/mounts (see fstab(5))
+func parseMounts(data string) (mounts []Mount) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Device:     unescapeMountField(fields[0]),
+			MountPoint: unescapeMountField(fields[1]),
+			Type:       fields[2],
+			Options:    strings.Split(fields[3], ","),
+		})
+	}
+	return mounts
+}
+
+// Mounts returns all of the currently mounted filesystems, from /proc/mounts
+func Mounts() ([]Mount, error) {
+	data, err := ioutil.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	return parseMounts(string(data)), nil
+}
+
+// FileHandles returns the three figures from /proc/sys/fs/file-nr: the number
+// of allocated file handles, the number of allocated but unused ones, and the
+// system-wide maximum
+func FileHandles() (allocated, unused, max uint64, err error) {
+	data, err := ioutil.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return 0, 0, 0, fmt.Errorf("Unexpected format in file-nr: %q", data)
+	}
+	var figures [3]uint64
+	for i, field := range fields {
+		figures[i], err = strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return figures[0], figures[1], figures[2], nil
+}
+
 // how many inodes are in this given state? state can be one of:
 // total, used, free, percent
 func inodesInState(filesystem, state string) (total uint64, err error) {
@@ -1,7 +1,12 @@
 package fsstatus
 
 import (
+	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -128,3 +133,222 @@ func TestInodePercentFunction(t *testing.T) {
 		t.Errorf(msg, calculatedPercent, givenPercent)
 	}
 }
+
+func TestDirectorySize(t *testing.T) {
+	t.Parallel()
+	// set up a tree with a hard link, a symlink loop, and a link to an
+	// outside file
+	root, err := ioutil.TempDir("", "distributive-dirsize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	outside, err := ioutil.TempFile("", "distributive-dirsize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(outside.Name())
+	outside.Write(make([]byte, 5))
+	outside.Close()
+	os.Mkdir(filepath.Join(root, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "a"), make([]byte, 10), 0644)
+	ioutil.WriteFile(filepath.Join(root, "sub", "b"), make([]byte, 20), 0644)
+	os.Link(filepath.Join(root, "a"), filepath.Join(root, "hardlink"))
+	os.Symlink(root, filepath.Join(root, "sub", "loop"))
+	os.Symlink(outside.Name(), filepath.Join(root, "outside"))
+
+	for follow, expected := range map[bool]uint64{false: 30, true: 35} {
+		actual, skipped, err := DirectorySize(root, follow)
+		if err != nil {
+			t.Error(err)
+		} else if actual != expected {
+			msg := "DirectorySize returned %v, expected %v (follow: %v)"
+			t.Errorf(msg, actual, expected, follow)
+		} else if len(skipped) > 0 {
+			t.Errorf("DirectorySize skipped readable entries: %v", skipped)
+		}
+	}
+	if _, _, err := DirectorySize("/steppenwolf", false); err == nil {
+		t.Error("DirectorySize didn't return an error for a nonexistent dir")
+	}
+	// root can read the directory regardless of its permissions
+	if os.Geteuid() == 0 {
+		return
+	}
+	locked := filepath.Join(root, "locked")
+	os.Mkdir(locked, 0755)
+	ioutil.WriteFile(filepath.Join(locked, "c"), make([]byte, 40), 0644)
+	os.Chmod(locked, 0)
+	defer os.Chmod(locked, 0755)
+	actual, skipped, err := DirectorySize(root, false)
+	if err != nil {
+		t.Errorf("DirectorySize failed on an unreadable subdirectory: %v", err)
+	} else if actual != 30 {
+		t.Errorf("DirectorySize returned %v, expected 30", actual)
+	} else if len(skipped) != 1 || skipped[0] != locked {
+		t.Errorf("Expected DirectorySize to skip %v, skipped %v", locked, skipped)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "distributive-firstdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// big enough to span several reads
+	data := make([]byte, 100*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	changed := append([]byte{}, data...)
+	changed[70000] = ^changed[70000]
+	files := map[string][]byte{
+		"a": data, "b": data, "changed": changed, "prefix": data[:50000],
+		"empty": []byte{},
+	}
+	for name, contents := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), contents, 0644)
+	}
+	expected := map[[2]string]int64{
+		{"a", "b"}:          -1,
+		{"a", "changed"}:    70000,
+		{"a", "prefix"}:     50000,
+		{"prefix", "a"}:     50000,
+		{"empty", "empty"}:  -1,
+		{"empty", "prefix"}: 0,
+	}
+	for pair, offset := range expected {
+		actual, err := FirstDifference(filepath.Join(dir, pair[0]),
+			filepath.Join(dir, pair[1]))
+		if err != nil {
+			t.Error(err)
+		} else if actual != offset {
+			msg := "FirstDifference(%v, %v) returned %v, expected %v"
+			t.Errorf(msg, pair[0], pair[1], actual, offset)
+		}
+	}
+	if _, err := FirstDifference("/steppenwolf", "/dev/null"); err == nil {
+		t.Error("FirstDifference didn't return an error for a nonexistent file")
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	t.Parallel()
+	data := "/dev/sda1 / ext4 rw,relatime 0 0\n"
+	data += "/dev/sdb1 /mnt/my\\040disk ext4 rw,nosuid,nodev,noexec 0 0\n"
+	data += "tmpfs /tmp tmpfs rw,size=1024k,mode=1777 0 0\n\n"
+	mounts := parseMounts(data)
+	if len(mounts) != 3 {
+		t.Fatalf("parseMounts returned %d mounts, expected 3", len(mounts))
+	}
+	if mounts[1].MountPoint != "/mnt/my disk" || mounts[1].Type != "ext4" {
+		t.Errorf("parseMounts didn't parse mount: %+v", mounts[1])
+	}
+	options := map[string]bool{
+		"noexec": false, "rw": true, "size": false, "size=1024k": false,
+		"size=2048k": false, "mode": false,
+	}
+	expected := []map[string]bool{
+		options,
+		{"noexec": true, "nodev": true, "rw": true, "mode": false},
+		{"size": true, "size=1024k": true, "size=2048k": false, "mode": true},
+	}
+	for i, mount := range mounts {
+		for option, has := range expected[i] {
+			if mount.HasOption(option) != has {
+				msg := "%v.HasOption(%v) returned %v, expected %v"
+				t.Errorf(msg, mount.MountPoint, option, !has, has)
+			}
+		}
+	}
+	if _, err := Mounts(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileHandles(t *testing.T) {
+	t.Parallel()
+	allocated, unused, max, err := FileHandles()
+	if err != nil {
+		t.Error(err)
+	} else if max == 0 || unused > allocated {
+		msg := "FileHandles returned implausible figures: %v, %v, %v"
+		t.Errorf(msg, allocated, unused, max)
+	}
+}
+
+func TestHeadTailLines(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsstatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var lines []string
+	for i := 0; i < 2000; i++ { // long enough to span several chunks
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	path := filepath.Join(dir, "lines")
+	data := strings.Join(lines, "\n") + "\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		n          int
+		head, tail []string
+	}{
+		{0, []string{}, []string{}},
+		{2, lines[:2], lines[1998:]},
+		{1000, lines[:1000], lines[1000:]},
+		{5000, lines, lines},
+	}
+	for _, test := range tests {
+		head, err := HeadLines(path, test.n)
+		if err != nil {
+			t.Error(err)
+		} else if fmt.Sprint(head) != fmt.Sprint(test.head) {
+			t.Errorf("Unexpected first %d lines: %v", test.n, head)
+		}
+		tail, err := TailLines(path, test.n)
+		if err != nil {
+			t.Error(err)
+		} else if fmt.Sprint(tail) != fmt.Sprint(test.tail) {
+			t.Errorf("Unexpected last %d lines: %v", test.n, tail)
+		}
+	}
+	if tail, err := TailLines("/proc/self/status", 1); err != nil || len(tail) != 1 {
+		t.Errorf("Couldn't tail a file in /proc: %v, %v", tail, err)
+	}
+}
+
+func TestCountString(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsstatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// put one occurrence across the boundary between the first two chunks
+	padding := strings.Repeat(".", 32*1024-13)
+	data := "server {}\n" + padding + "server {}\nserver {}" + padding + "aaaaa"
+	path := filepath.Join(dir, "nginx.conf")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]uint64{
+		"server {": 3, "}\nserver": 1, "aa": 2, "aaaaa": 1, "bees": 0,
+		padding: 2, padding + ".": 0,
+	}
+	for substr, expected := range tests {
+		if actual, err := CountString(path, substr); err != nil {
+			t.Error(err)
+		} else if actual != expected {
+			t.Errorf("Expected %d occurrences of %.20q, got %d", expected, substr, actual)
+		}
+	}
+	if _, err := CountString(path, ""); err == nil {
+		t.Error("Expected an error counting the empty string")
+	}
+}
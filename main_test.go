@@ -35,3 +35,16 @@ func testGetChecklists(t *testing.T) {
 		lengthError(1, len(chklsts))
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	// failed, threshold, expected exit code
+	cases := [][3]int{
+		{0, 0, 0}, {1, 0, 1}, {3, 3, 0}, {4, 3, 1}, {2, 5, 0}, {0, 5, 0},
+	}
+	for _, c := range cases {
+		if actual := exitCode(c[0], c[1]); actual != c[2] {
+			msg := "exitCode(%v, %v) returned %v, expected %v"
+			t.Errorf(msg, c[0], c[1], actual, c[2])
+		}
+	}
+}
@@ -5,12 +5,45 @@ package memstatus
 import (
 	"errors"
 	"github.com/zeldal/distributive/tabular"
+	"io/ioutil"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// parseMemInfo parses data in the format of /proc/meminfo, e.g.
+// "MemTotal:  8048576 kB" -> {"MemTotal": 8048576}
+func parseMemInfo(data string) (map[string]uint64, error) {
+	info := make(map[string]uint64)
+	for _, line := range strings.Split(data, "\n") {
+		keyValue := strings.SplitN(line, ":", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		fields := strings.Fields(keyValue[1])
+		if len(fields) < 1 {
+			return info, errors.New("No value in meminfo line: " + line)
+		}
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return info, errors.New("Couldn't parse meminfo line: " + line)
+		}
+		info[strings.TrimSpace(keyValue[0])] = value
+	}
+	return info, nil
+}
+
+// MemInfo returns the fields of /proc/meminfo. Sizes are given in kB, like in
+// the file itself, while counts (like HugePages_Total) have no units.
+func MemInfo() (map[string]uint64, error) {
+	data, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	return parseMemInfo(string(data))
+}
+
 // swapOrMemory returns output from `free`, it is an abstraction of swap and
 // memory. inputs: status: free | used | total; swapOrMem: memory | swap;
 // units: b | kb | mb | gb | tb
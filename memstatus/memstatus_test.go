@@ -74,3 +74,28 @@ func TestUsedSwap(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMemInfo(t *testing.T) {
+	t.Parallel()
+	data := "MemTotal:        8048576 kB\nHugePages_Total:      16\n"
+	data += "HugePages_Free:        4\nHugepagesize:       2048 kB\n"
+	info, err := parseMemInfo(data)
+	expected := map[string]uint64{
+		"MemTotal": 8048576, "HugePages_Total": 16, "HugePages_Free": 4,
+		"Hugepagesize": 2048,
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	for key, value := range expected {
+		if info[key] != value {
+			t.Errorf("parseMemInfo gave %v for %v, expected %v", info[key], key, value)
+		}
+	}
+	if _, err := parseMemInfo("MemTotal: lots kB\n"); err == nil {
+		t.Error("parseMemInfo didn't return an error on invalid input")
+	}
+	if info, err := MemInfo(); err != nil || info["MemTotal"] == 0 {
+		t.Errorf("MemInfo failed: %v", err)
+	}
+}
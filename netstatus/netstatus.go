@@ -3,14 +3,21 @@
 package netstatus
 
 import (
+	"errors"
 	"fmt"
 	"github.com/zeldal/distributive/chkutil"
 	"github.com/zeldal/distributive/tabular"
 	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
 	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -73,6 +80,163 @@ func OpenPorts(protocol string) (ports []uint16) {
 	return ports
 }
 
+// TCP socket states, as hex strings in the st column of /proc/net/tcp
+const (
+	TCPEstablished = "01"
+	TCPTimeWait    = "06"
+	TCPCloseWait   = "08"
+	TCPListen      = "0A"
+)
+
+// TCPSocket is one entry of /proc/net/tcp or /proc/net/tcp6
+type TCPSocket struct {
+	LocalPort uint16
+	State     string // see TCPListen, etc.
+	Inode     uint64
+}
+
+// TCPSockets returns all the TCP sockets (on both IPv4 and IPv6), as found in
+// /proc/net/tcp and /proc/net/tcp6
+func TCPSockets() (sockets []TCPSocket, err error) {
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) { // IPv6 might be disabled
+			continue
+		} else if err != nil {
+			return sockets, err
+		}
+		// columns are: sl local_address rem_address st ... uid timeout inode
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 10 {
+				continue
+			}
+			address := strings.Split(fields[1], ":")
+			port, err := strconv.ParseUint(address[len(address)-1], 16, 16)
+			if err != nil {
+				return sockets, err
+			}
+			inode, err := strconv.ParseUint(fields[9], 10, 64)
+			if err != nil {
+				return sockets, err
+			}
+			socket := TCPSocket{uint16(port), strings.ToUpper(fields[3]), inode}
+			sockets = append(sockets, socket)
+		}
+	}
+	return sockets, nil
+}
+
+// ListeningSockets maps the inodes of all listening TCP sockets (on both IPv4
+// and IPv6) to their (decimal) ports, from /proc/net/tcp and /proc/net/tcp6
+func ListeningSockets() (map[uint64]uint16, error) {
+	listening := make(map[uint64]uint16)
+	sockets, err := TCPSockets()
+	if err != nil {
+		return listening, err
+	}
+	for _, socket := range sockets {
+		if socket.State == TCPListen {
+			listening[socket.Inode] = socket.LocalPort
+		}
+	}
+	return listening, nil
+}
+
+// PIDsByName returns the pids of all processes with the given name, either as
+// found in /proc/[pid]/comm, or as the base name of their first argument.
+func PIDsByName(name string) (pids []int, err error) {
+	dirs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return pids, err
+	}
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil || !dir.IsDir() {
+			continue
+		}
+		// processes can exit while we're looking at them, so ignore errors
+		procDir := filepath.Join("/proc", dir.Name())
+		comm, _ := ioutil.ReadFile(filepath.Join(procDir, "comm"))
+		cmdline, _ := ioutil.ReadFile(filepath.Join(procDir, "cmdline"))
+		arg0 := strings.SplitN(string(cmdline), "\x00", 2)[0]
+		if strings.TrimSpace(string(comm)) == name || filepath.Base(arg0) == name {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// SocketInodes returns the inodes of all the sockets that this process has
+// open, as found in /proc/[pid]/fd. Reading the file descriptors of other
+// users' processes generally requires root.
+func SocketInodes(pid int) (inodes []uint64, err error) {
+	fdDir := filepath.Join("/proc", fmt.Sprint(pid), "fd")
+	fds, err := ioutil.ReadDir(fdDir)
+	if err != nil {
+		return inodes, err
+	}
+	socketRe := regexp.MustCompile(`^socket:\[(\d+)\]$`)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil {
+			continue // closed in the meantime
+		}
+		if match := socketRe.FindStringSubmatch(link); match != nil {
+			inode, err := strconv.ParseUint(match[1], 10, 64)
+			if err != nil {
+				return inodes, err
+			}
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
+
+// listeningPortsOf returns the ports of the given listening sockets that any of
+// the pids have open. Pids that have exited since they were found, or whose
+// file descriptors can't be read (like a master process owned by root), are
+// skipped. It only fails if none of them could be read.
+func listeningPortsOf(pids []int, sockets map[uint64]uint16) (ports []uint16, err error) {
+	seen := make(map[uint16]bool)
+	read := 0
+	for _, pid := range pids {
+		inodes, inodesErr := SocketInodes(pid)
+		if os.IsNotExist(inodesErr) || os.IsPermission(inodesErr) {
+			err = inodesErr
+			continue
+		} else if inodesErr != nil {
+			return ports, inodesErr
+		}
+		read++
+		for _, inode := range inodes {
+			if port, ok := sockets[inode]; ok && !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	if read > 0 {
+		return ports, nil
+	}
+	return ports, err
+}
+
+// ProcessListeningPorts returns the TCP ports that any process with the given
+// name is listening on, along with the pids of those processes.
+func ProcessListeningPorts(name string) (ports []uint16, pids []int, err error) {
+	pids, err = PIDsByName(name)
+	if err != nil || len(pids) < 1 {
+		return ports, pids, err
+	}
+	sockets, err := ListeningSockets()
+	if err != nil {
+		return ports, pids, err
+	}
+	ports, err = listeningPortsOf(pids, sockets)
+	return ports, pids, err
+}
+
 // PortOpen reports whether or not the given (decimal) port is open
 // Its protocol argument can only be one of: "tcp" | "udp"
 func PortOpen(protocol string, port uint16) bool {
@@ -127,6 +291,345 @@ func InterfaceIPs(name string) (ifaceAddresses []*net.IP) {
 	return nil // will be empty
 }
 
+// InterfaceBytes returns the number of bytes this interface has received (if
+// direction is "rx") or transmitted (if it is "tx") so far, as reported by
+// /sys/class/net/<interface>/statistics
+func InterfaceBytes(name string, direction string) (uint64, error) {
+	path := filepath.Join("/sys/class/net", name, "statistics", direction+"_bytes")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// InterfaceDuplex returns the duplex of this interface (full or half) as
+// reported by /sys/class/net/<interface>/duplex, or "unknown" if the interface
+// has no carrier or is virtual.
+func InterfaceDuplex(name string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/sys/class/net", name, "duplex"))
+	if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EINVAL {
+		return "unknown", nil // virtual interfaces can't report their duplex
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// DHCPLease is a lease obtained by a DHCP client for an interface. A zero
+// Expiry means that the lease never expires.
+type DHCPLease struct {
+	Interface string
+	Address   net.IP
+	Expiry    time.Time
+}
+
+// Expired reports whether the lease had expired by the given time
+func (lease DHCPLease) Expired(now time.Time) bool {
+	return !lease.Expiry.IsZero() && now.After(lease.Expiry)
+}
+
+// dhclientLeaseGlobs are where dhclient keeps its leases on various distros
+var dhclientLeaseGlobs = []string{
+	"/var/lib/dhcp/*.lease*",
+	"/var/lib/dhclient/*.lease*",
+	"/var/lib/NetworkManager/*.lease*",
+}
+
+// networkdLeaseDir is where systemd-networkd keeps its leases, one file per
+// interface, named by interface index
+var networkdLeaseDir = "/run/systemd/netif/leases"
+
+// parseDhclientLeases parses the lease blocks in a dhclient.leases(5) file
+func parseDhclientLeases(data string) (leases []DHCPLease) {
+	optionRe := regexp.MustCompile(`^\s*(\S+)\s+(.*?);`)
+	var lease *DHCPLease
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "lease {"):
+			lease = &DHCPLease{}
+		case line == "}" && lease != nil:
+			leases = append(leases, *lease)
+			lease = nil
+		case lease != nil:
+			match := optionRe.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			switch match[1] {
+			case "interface":
+				lease.Interface = strings.Trim(match[2], `"`)
+			case "fixed-address":
+				lease.Address = net.ParseIP(match[2])
+			case "expire":
+				lease.Expiry = parseDhclientTime(match[2])
+			}
+		}
+	}
+	return leases
+}
+
+// parseDhclientTime parses the time of a dhclient lease statement, which is
+// one of "never", "epoch <seconds>", or "<weekday> YYYY/MM/DD HH:MM:SS" in UTC
+func parseDhclientTime(str string) time.Time {
+	fields := strings.Fields(str)
+	if len(fields) == 2 && fields[0] == "epoch" {
+		if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			return time.Unix(seconds, 0)
+		}
+	} else if len(fields) == 3 {
+		layout := "2006/01/02 15:04:05"
+		if t, err := time.Parse(layout, fields[1]+" "+fields[2]); err == nil {
+			return t
+		}
+	}
+	return time.Time{} // never
+}
+
+// parseNetworkdLease parses a systemd-networkd lease file, whose expiry is
+// its LIFETIME in seconds after it was written at modified.
+func parseNetworkdLease(data string, modified time.Time) (lease DHCPLease) {
+	for _, line := range strings.Split(data, "\n") {
+		keyValue := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		switch keyValue[0] {
+		case "ADDRESS":
+			lease.Address = net.ParseIP(keyValue[1])
+		case "LIFETIME":
+			seconds, err := strconv.ParseInt(keyValue[1], 10, 64)
+			if err == nil {
+				lease.Expiry = modified.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+	return lease
+}
+
+// DHCPLeases returns all the leases dhclient and systemd-networkd have on
+// record for the named interface.
+func DHCPLeases(name string) (leases []DHCPLease, err error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(networkdLeaseDir, fmt.Sprint(iface.Index))
+	if info, err := os.Stat(path); err == nil {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lease := parseNetworkdLease(string(data), info.ModTime())
+		lease.Interface = name
+		leases = append(leases, lease)
+	}
+	for _, glob := range dhclientLeaseGlobs {
+		paths, _ := filepath.Glob(glob)
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+			if os.IsPermission(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			for _, lease := range parseDhclientLeases(string(data)) {
+				if lease.Interface == name {
+					leases = append(leases, lease)
+				}
+			}
+		}
+	}
+	return leases, nil
+}
+
+// vlanIDRe matches the VLAN id in /proc/net/vlan/<interface> ("VID: 100")
+// and in the output of `ip -d link show` ("vlan protocol 802.1Q id 100")
+var vlanIDRe = regexp.MustCompile(`(?:VID:|vlan protocol \S+ id|vlan id)\s+(\d+)`)
+
+// parseVLANID finds the VLAN id in the given description of an interface,
+// returning false if it doesn't describe a VLAN interface.
+func parseVLANID(data string) (uint16, bool) {
+	match := vlanIDRe.FindStringSubmatch(data)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(match[1], 10, 16)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(id), true
+}
+
+// VLANID returns the VLAN id of the named interface, as reported by
+// /proc/net/vlan/<interface> if the 8021q module provides it, or by
+// `ip -d link show` otherwise. The boolean is false if the interface exists
+// but isn't a VLAN interface.
+func VLANID(name string) (uint16, bool, error) {
+	if _, err := net.InterfaceByName(name); err != nil {
+		return 0, false, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join("/proc/net/vlan", name))
+	if os.IsNotExist(err) {
+		// with 8021q loaded, only VLAN interfaces have entries here
+		if _, err := os.Stat("/proc/net/vlan/config"); err == nil {
+			return 0, false, nil
+		}
+	}
+	if err != nil {
+		out, err := exec.Command("ip", "-d", "link", "show", name).Output()
+		if err != nil {
+			return 0, false, err
+		}
+		data = out
+	}
+	id, ok := parseVLANID(string(data))
+	return id, ok, nil
+}
+
+// Route is an entry in the kernel's IPv4 routing table
+type Route struct {
+	Interface                  string
+	Destination, Gateway, Mask net.IP
+	Flags                      uint16
+	Metric                     uint32
+}
+
+// parseHexIP parses an IPv4 address as /proc/net/route writes it: eight hex
+// digits in host (little-endian) byte order
+func parseHexIP(hexIP string) (net.IP, error) {
+	n, err := strconv.ParseUint(hexIP, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(byte(n), byte(n>>8), byte(n>>16), byte(n>>24)), nil
+}
+
+// parseRoutes parses the contents of /proc/net/route
+func parseRoutes(data string) (routes []Route, err error) {
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	for _, line := range lines[1:] { // the first line is the header
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("Malformed line in routing table: %q", line)
+		}
+		var route Route
+		route.Interface = fields[0]
+		if route.Destination, err = parseHexIP(fields[1]); err != nil {
+			return nil, err
+		} else if route.Gateway, err = parseHexIP(fields[2]); err != nil {
+			return nil, err
+		} else if route.Mask, err = parseHexIP(fields[7]); err != nil {
+			return nil, err
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		metric, err := strconv.ParseUint(fields[6], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		route.Flags = uint16(flags)
+		route.Metric = uint32(metric)
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// routeCacheTTL is how long a snapshot of the routing table is reused, so that
+// checks run together see the same table and don't each reread it
+const routeCacheTTL = 5 * time.Second
+
+var routeCache struct {
+	sync.Mutex
+	routes []Route
+	taken  time.Time
+}
+
+// RoutingTable returns the kernel's IPv4 routing table, as read from
+// /proc/net/route. The snapshot is shared between calls made within
+// routeCacheTTL of each other.
+func RoutingTable() ([]Route, error) {
+	routeCache.Lock()
+	defer routeCache.Unlock()
+	if routeCache.routes != nil && time.Since(routeCache.taken) < routeCacheTTL {
+		return routeCache.routes, nil
+	}
+	data, err := ioutil.ReadFile("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseRoutes(string(data))
+	if err != nil {
+		return nil, err
+	}
+	routeCache.routes, routeCache.taken = routes, time.Now()
+	return routes, nil
+}
+
+// ErrNoRawSockets is returned by functions that need raw sockets (and so
+// Linux and CAP_NET_RAW) when they can't open them
+var ErrNoRawSockets = errors.New("Opening a raw socket was not permitted (needs Linux and CAP_NET_RAW)")
+
+// icmpChecksum computes the internet checksum (RFC 1071) of an ICMP message
+func icmpChecksum(msg []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	if len(msg)%2 == 1 {
+		sum += uint32(msg[len(msg)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// echoRequest builds an ICMP echo request with this id and sequence number,
+// padded to size bytes
+func echoRequest(id, seq uint16, size int) []byte {
+	msg := make([]byte, size)
+	msg[0] = 8 // echo request
+	msg[4], msg[5] = byte(id>>8), byte(id)
+	msg[6], msg[7] = byte(seq>>8), byte(seq)
+	checksum := icmpChecksum(msg)
+	msg[2], msg[3] = byte(checksum>>8), byte(checksum)
+	return msg
+}
+
+// echoResult interprets an IPv4 packet received on a raw ICMP socket. It
+// returns whether it's a reply to the echo request with this id and sequence
+// number, and whether it's a "fragmentation needed" error about that request.
+func echoResult(packet []byte, id, seq uint16) (reply, fragNeeded bool) {
+	// matches checks the id and sequence number of an ICMP echo header
+	matches := func(icmp []byte) bool {
+		return len(icmp) >= 8 &&
+			uint16(icmp[4])<<8|uint16(icmp[5]) == id &&
+			uint16(icmp[6])<<8|uint16(icmp[7]) == seq
+	}
+	if len(packet) < 20 {
+		return false, false
+	}
+	icmp := packet[int(packet[0]&0x0f)*4:]
+	if len(icmp) < 8 {
+		return false, false
+	}
+	switch {
+	case icmp[0] == 0 && icmp[1] == 0: // echo reply
+		return matches(icmp), false
+	case icmp[0] == 3 && icmp[1] == 4: // fragmentation needed
+		inner := icmp[8:] // the header of the packet that was too big
+		if len(inner) < 20 {
+			return false, false
+		}
+		return false, matches(inner[int(inner[0]&0x0f)*4:])
+	}
+	return false, false
+}
+
 // Resolvable checks if the given host can be resolved on the TCP and UDP nets
 func Resolvable(host string) bool {
 	_, err := net.LookupHost(host)
@@ -136,6 +639,19 @@ func Resolvable(host string) bool {
 	return false
 }
 
+// ConnectTime opens (and closes) a TCP connection to host, returning how long
+// it took to be established
+func ConnectTime(host string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	conn.Close()
+	return elapsed, nil
+}
+
 // CanConnect tests whether a connection can be made to a given host on its
 // given port using protocol ("TCP"|"UDP")
 func CanConnect(host string, protocol string, timeout time.Duration) bool {
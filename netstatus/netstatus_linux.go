@@ -0,0 +1,68 @@
+package netstatus
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// PingDontFragment sends an ICMP echo request of the given size (including
+// the 20 byte IP header) to this IPv4 address with the don't-fragment bit
+// set, and reports whether a reply came back within the timeout.
+func PingDontFragment(ip net.IP, size int, timeout time.Duration) (bool, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return false, errors.New("Not an IPv4 address: " + ip.String())
+	} else if size < 28 {
+		return false, fmt.Errorf("Packet size too small for ICMP echo: %d", size)
+	}
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_ICMP)
+	if err == syscall.EPERM || err == syscall.EACCES {
+		return false, ErrNoRawSockets
+	} else if err != nil {
+		return false, err
+	}
+	defer syscall.Close(fd)
+	// IP_PMTUDISC_PROBE sets the don't-fragment bit but ignores the cached
+	// path MTU, so that every size is actually sent
+	err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_PROBE)
+	if err != nil {
+		return false, err
+	}
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		return false, err
+	}
+	id := uint16(os.Getpid())
+	seq := uint16(time.Now().UnixNano())
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], ip4)
+	err = syscall.Sendto(fd, echoRequest(id, seq, size-20), 0, addr)
+	if err == syscall.EMSGSIZE { // bigger than the local interface's MTU
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	buf := make([]byte, 65536)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		reply, fragNeeded := echoResult(buf[:n], id, seq)
+		if fragNeeded {
+			return false, nil
+		} else if sender, ok := from.(*syscall.SockaddrInet4); reply && ok &&
+			net.IP(sender.Addr[:]).Equal(ip4) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
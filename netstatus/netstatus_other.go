@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package netstatus
+
+import (
+	"net"
+	"time"
+)
+
+// PingDontFragment needs Linux's IP_PMTUDISC_PROBE to set the don't-fragment
+// bit, so on other platforms it always returns ErrNoRawSockets.
+func PingDontFragment(ip net.IP, size int, timeout time.Duration) (bool, error) {
+	return false, ErrNoRawSockets
+}
@@ -2,6 +2,9 @@ package netstatus
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -142,3 +145,210 @@ func TestResolvable(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessListeningPorts(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	ports, pids, err := ProcessListeningPorts(filepath.Base(os.Args[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range ports {
+		found = found || p == port
+	}
+	if !found {
+		t.Errorf("ProcessListeningPorts didn't report port %d: %v", port, ports)
+	}
+	if len(pids) < 1 {
+		t.Error("ProcessListeningPorts didn't find this process")
+	}
+	ports, pids, err = ProcessListeningPorts("lkjashldfb")
+	if err != nil || len(ports) > 0 || len(pids) > 0 {
+		t.Errorf("ProcessListeningPorts found nonexistent process: %v", pids)
+	}
+}
+
+func TestListeningPortsOf(t *testing.T) {
+	t.Parallel()
+	// a pid that's too large to exist, like one that has exited
+	exited := 1 << 30
+	if _, err := listeningPortsOf([]int{os.Getpid(), exited}, nil); err != nil {
+		t.Errorf("listeningPortsOf failed because one pid had exited: %v", err)
+	}
+	if _, err := listeningPortsOf([]int{exited}, nil); err == nil {
+		t.Error("listeningPortsOf didn't fail when no pid could be read")
+	}
+}
+
+func TestInterfaceBytes(t *testing.T) {
+	t.Parallel()
+	for _, direction := range []string{"rx", "tx"} {
+		if _, err := InterfaceBytes("lo", direction); err != nil {
+			t.Errorf("InterfaceBytes failed on lo: %s", err.Error())
+		}
+		if _, err := InterfaceBytes("lkjashldfb", direction); !os.IsNotExist(err) {
+			t.Errorf("InterfaceBytes found nonexistent interface: %v", err)
+		}
+	}
+}
+
+func TestTCPSockets(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	sockets, err := TCPSockets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, socket := range sockets {
+		if socket.LocalPort == port && socket.State == TCPListen {
+			return
+		}
+	}
+	t.Errorf("TCPSockets didn't report listening port %d", port)
+}
+
+func TestParseDhclientLeases(t *testing.T) {
+	t.Parallel()
+	data := `lease {
+  interface "eth0";
+  fixed-address 192.168.1.20;
+  option subnet-mask 255.255.255.0;
+  renew 4 2016/01/07 06:00:00;
+  expire 4 2016/01/07 12:00:00;
+}
+lease {
+  interface "eth1";
+  fixed-address 10.0.0.5;
+  expire epoch 1452168000; # Thu Jan 07 12:00:00 2016
+}
+lease {
+  interface "eth2";
+  fixed-address 10.0.1.5;
+  expire never;
+}
+`
+	leases := parseDhclientLeases(data)
+	if len(leases) != 3 {
+		t.Fatalf("Expected 3 leases, got %d: %v", len(leases), leases)
+	}
+	expiry := time.Date(2016, 1, 7, 12, 0, 0, 0, time.UTC)
+	for _, lease := range leases[:2] {
+		if !lease.Expiry.Equal(expiry) {
+			t.Errorf("Expected %v to expire at %v", lease, expiry)
+		}
+	}
+	if leases[0].Interface != "eth0" || !leases[0].Address.Equal(net.ParseIP("192.168.1.20")) {
+		t.Errorf("Misparsed lease: %v", leases[0])
+	}
+	if leases[2].Expired(time.Now()) || !leases[0].Expired(time.Now()) {
+		t.Errorf("Misjudged lease expiries: %v", leases)
+	}
+}
+
+func TestParseNetworkdLease(t *testing.T) {
+	t.Parallel()
+	data := "# This is private data. Do not parse.\nADDRESS=10.0.2.15\n" +
+		"NETMASK=255.255.255.0\nLIFETIME=86400\n"
+	modified := time.Date(2016, 1, 7, 12, 0, 0, 0, time.UTC)
+	lease := parseNetworkdLease(data, modified)
+	if !lease.Address.Equal(net.ParseIP("10.0.2.15")) {
+		t.Errorf("Expected address 10.0.2.15, got %v", lease.Address)
+	}
+	if !lease.Expiry.Equal(modified.Add(24 * time.Hour)) {
+		t.Errorf("Expected expiry a day after %v, got %v", modified, lease.Expiry)
+	}
+}
+
+func TestParseVLANID(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		data   string
+		id     uint16
+		isVLAN bool
+	}{
+		{"eth0.100  VID: 100\t REORDER_HDR: 1  dev->priv_flags: 1001\n", 100, true},
+		{"5: eth0.20@eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500\n" +
+			"    link/ether 52:54:00:12:34:56 brd ff:ff:ff:ff:ff:ff promiscuity 0\n" +
+			"    vlan protocol 802.1Q id 20 <REORDER_HDR> addrgenmode eui64\n", 20, true},
+		{"1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 qdisc noqueue state UNKNOWN\n" +
+			"    link/loopback 00:00:00:00:00:00 brd 00:00:00:00:00:00\n", 0, false},
+	}
+	for _, test := range tests {
+		id, isVLAN := parseVLANID(test.data)
+		if id != test.id || isVLAN != test.isVLAN {
+			t.Errorf("Expected (%d, %v), got (%d, %v) for %q",
+				test.id, test.isVLAN, id, isVLAN, test.data)
+		}
+	}
+}
+
+func TestInterfaceDuplex(t *testing.T) {
+	t.Parallel()
+	if duplex, err := InterfaceDuplex("lo"); err != nil || duplex != "unknown" {
+		t.Errorf("Expected lo to have unknown duplex, got %q, %v", duplex, err)
+	}
+	if _, err := InterfaceDuplex("lkjashldfb"); !os.IsNotExist(err) {
+		t.Errorf("Expected a nonexistent interface to be reported, got %v", err)
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	t.Parallel()
+	data := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t010200C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t000200C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"
+	routes, err := parseRoutes(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %d: %v", len(routes), routes)
+	}
+	def := routes[0]
+	if !def.Gateway.Equal(net.ParseIP("192.0.2.1")) || def.Metric != 100 ||
+		def.Flags != 3 || !def.Destination.Equal(net.IPv4zero) {
+		t.Errorf("Misparsed default route: %+v", def)
+	}
+	if !routes[1].Destination.Equal(net.ParseIP("192.0.2.0")) ||
+		!routes[1].Mask.Equal(net.ParseIP("255.255.255.0")) {
+		t.Errorf("Misparsed subnet route: %+v", routes[1])
+	}
+	if _, err := parseRoutes("Iface\tDestination\neth0\t00000000\n"); err == nil {
+		t.Error("Expected an error for a malformed routing table")
+	}
+}
+
+func TestEchoResult(t *testing.T) {
+	t.Parallel()
+	ipHeader := make([]byte, 20)
+	ipHeader[0] = 0x45 // version 4, 20 byte header
+	request := echoRequest(0x1234, 7, 8)
+	if icmpChecksum(request) != 0 {
+		t.Errorf("Echo request had a bad checksum: %v", request)
+	}
+	reply := append([]byte{}, request...)
+	reply[0] = 0
+	packet := append(append([]byte{}, ipHeader...), reply...)
+	if ok, _ := echoResult(packet, 0x1234, 7); !ok {
+		t.Error("Didn't recognize echo reply")
+	}
+	if ok, _ := echoResult(packet, 0x1234, 8); ok {
+		t.Error("Recognized echo reply with the wrong sequence number")
+	}
+	fragNeeded := append(append(append([]byte{}, ipHeader...),
+		3, 4, 0, 0, 0, 0, 0x05, 0x78), ipHeader...)
+	fragNeeded = append(fragNeeded, request...)
+	if _, ok := echoResult(fragNeeded, 0x1234, 7); !ok {
+		t.Error("Didn't recognize fragmentation needed error")
+	}
+}
@@ -113,6 +113,11 @@ func getFlags() (f string, u string, d string, s bool) {
 			Name:  "no-cache",
 			Usage: "Don't use a cached version of a remote check, fetch it.",
 		},
+		cli.IntFlag{
+			Name:  "fail-threshold",
+			Value: 0,
+			Usage: "Only exit non-zero if more than this many checks fail",
+		},
 	}
 	var verbosity string
 	var file string
@@ -141,6 +146,12 @@ func getFlags() (f string, u string, d string, s bool) {
 			"stdin":     stdin,
 		}).Debug("Command line options")
 		useCache = !c.Bool("no-cache")
+		failThreshold = c.Int("fail-threshold")
+		if failThreshold < 0 {
+			log.WithFields(log.Fields{
+				"given": failThreshold,
+			}).Fatal("Failure threshold can't be negative")
+		}
 	}
 	if verbosity == "" {
 		verbosity = "warn"
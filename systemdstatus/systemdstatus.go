@@ -32,6 +32,22 @@ func ServiceActive(name string) (bool, error) {
 	return strings.Contains(string(out), "ActiveState=active"), nil
 }
 
+// UnitProperty returns the value of a property of a systemd unit, as shown by
+// `systemctl show -p <property> <unit>`
+func UnitProperty(unit string, property string) (string, error) {
+	cmd := exec.Command("systemctl", "show", "-p", property, unit)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(err.Error() + ": output: " + string(out))
+	}
+	for _, line := range tabular.Lines(string(out)) {
+		if strings.HasPrefix(line, property+"=") {
+			return strings.TrimPrefix(line, property+"="), nil
+		}
+	}
+	return "", errors.New("systemctl didn't show property " + property + " of " + unit)
+}
+
 // ListeningSockets returns a list of all sockets in the "LISTENING" state
 func ListeningSockets() (socks []string, err error) {
 	out, err := exec.Command("systemctl", "list-sockets").CombinedOutput()
@@ -60,8 +76,7 @@ func Timers(all bool) (timers []string, err error) {
 		return timers, errors.New(msg)
 	}
 	table := tabular.SeparateOnAlignment(tabular.Unlines(lines[:len(lines)-3]))
-	column := tabular.GetColumnByHeader("UNIT", table)
-	return column, nil
+	return tabular.GetColumnByHeaderMatching("UNIT", table, tabular.TolerantHeaders)
 }
 
 // UnitFileStatuses returns a list of all unit files with their current status,
@@ -73,21 +88,28 @@ func UnitFileStatuses() (units, statuses []string, err error) {
 		err2 := errors.New(err.Error() + ": output: " + string(out))
 		return units, statuses, err2
 	}
-	table := tabular.ProbabalisticSplit(string(out))
-	units = tabular.GetColumnNoHeader(0, table)
-	// last two are empty line and junk statistics we don't care about
-	if len(units) <= 3 {
+	// last line is junk statistics we don't care about
+	lines := tabular.Lines(strings.TrimSpace(string(out)))
+	if len(lines) < 3 {
 		msg := fmt.Sprint(cmd.Args) + " didn't output enough lines"
 		return units, statuses, errors.New(msg)
 	}
-	cmd = exec.Command("systemctl", "--no-pager", "list-unit-files")
-	table = tabular.ProbabalisticSplit(string(out))
-	statuses = tabular.GetColumnNoHeader(1, table)
-	// last two are empty line and junk statistics we don't care about
-	if len(statuses) <= 3 {
-		msg := fmt.Sprint(cmd.Args) + " didn't output enough lines"
+	table := tabular.ProbabalisticSplit(tabular.Unlines(lines[:len(lines)-1]))
+	// "UNIT FILE" and "VENDOR PRESET" can be split into two cells each, which
+	// shifts the columns after them
+	multiWord := []string{"UNIT FILE", "VENDOR PRESET"}
+	units, err = tabular.GetColumnByHeaderMatching("UNIT FILE", table,
+		tabular.TolerantHeaders, multiWord...)
+	if err != nil {
+		return units, statuses, err
+	}
+	statuses, err = tabular.GetColumnByHeaderMatching("STATE", table,
+		tabular.TolerantHeaders, multiWord...)
+	if err != nil {
+		return units, statuses, err
+	} else if len(units) != len(statuses) {
+		msg := fmt.Sprint(cmd.Args) + " had a different number of units and states"
 		return units, statuses, errors.New(msg)
 	}
-	return units[:len(units)-2], statuses[:len(statuses)-2], nil
-
+	return units, statuses, nil
 }
@@ -1,6 +1,8 @@
 package tabular
 
 import (
+	"errors"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"math"
 	"regexp"
@@ -226,3 +228,23 @@ func ProbabalisticSplit(str string) (output Table) {
 	}).Debug("ProbabalisticSplit chose a regexp")
 	return SeparateString(rowSep, colSep, str)
 }
+
+// SplitWithWidth is like ProbabalisticSplit, but ensures that every row of
+// the resulting table has exactly expectedCols cells, returning an error that
+// describes the first offending row if the split was inconsistent.
+func SplitWithWidth(str string, expectedCols int) (output Table, err error) {
+	if expectedCols < 1 {
+		msg := "Expected a positive number of columns, got "
+		return output, errors.New(msg + fmt.Sprint(expectedCols))
+	}
+	output = ProbabalisticSplit(str)
+	for i, row := range output {
+		if len(row) != expectedCols {
+			msg := "Inconsistent table split: row " + fmt.Sprint(i)
+			msg += " had " + fmt.Sprint(len(row)) + " columns, expected "
+			msg += fmt.Sprint(expectedCols) + ": " + fmt.Sprint(row)
+			return output, errors.New(msg)
+		}
+	}
+	return output, nil
+}
@@ -238,3 +238,19 @@ func TestProbabalisticSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitWithWidth(t *testing.T) {
+	t.Parallel()
+	// deliberately ragged input, with mixed tabs and spaces
+	ragged := "a\tb c\td\na  b\tc d\na b c d e\n"
+	inputs := append(inputTables, ragged, ragged, inputTables[0])
+	widths := []int{4, 4, 2, 9, 4, 5, 0}
+	outputs := []bool{true, false, true, false, false, false, false}
+	for i := range inputs {
+		expected := outputs[i]
+		_, err := SplitWithWidth(inputs[i], widths[i])
+		if actual := err == nil; actual != expected {
+			pureFunctionError(t, inputs[i], expected, actual)
+		}
+	}
+}
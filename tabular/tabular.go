@@ -4,6 +4,7 @@
 package tabular
 
 import (
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"regexp"
 	"strings"
@@ -263,29 +264,127 @@ func GetColumnNoHeader(col int, tab Table) Column {
 	return column[1:]
 }
 
+// HeaderMatching determines how strictly a column's header has to match the
+// name it is being looked up by.
+type HeaderMatching int
+
+const (
+	// ExactHeaders only matches a single cell in the first row of the table,
+	// ignoring case differences.
+	ExactHeaders HeaderMatching = iota
+	// TolerantHeaders also ignores surrounding and repeated whitespace,
+	// matches headers that were split into several cells (like "UNIT FILE" in
+	// `systemctl list-unit-files`), and looks past a title line above the
+	// headers (like "Kernel IP routing table" in `route -n`).
+	TolerantHeaders
+)
+
+// normalizeHeader trims, case-folds, and collapses the whitespace in a header
+func normalizeHeader(header string) string {
+	return strings.ToLower(strings.Join(strings.Fields(header), " "))
+}
+
+// headerSpan returns the number of cells at the start of row that together
+// make up the (normalized) header name, or 0 if they don't
+func headerSpan(name string, row []string) int {
+	joined := ""
+	for i, cell := range row {
+		joined = strings.TrimSpace(joined + " " + normalizeHeader(cell))
+		if joined == name {
+			return i + 1
+		} else if !strings.HasPrefix(name, joined+" ") {
+			return 0
+		}
+	}
+	return 0
+}
+
+// logicalHeaders normalizes the cells of a header row, joining those that
+// were split from one of the multi-word headers
+func logicalHeaders(row []string, multiWord []string) (headers []string) {
+	for i := 0; i < len(row); i++ {
+		header := normalizeHeader(row[i])
+		for _, name := range multiWord {
+			name = normalizeHeader(name)
+			if span := headerSpan(name, row[i:]); span > 1 {
+				header = name
+				i += span - 1
+				break
+			}
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// isTitleLine reports whether the first row of the table is a title above the
+// headers, rather than the headers themselves. It is if it is shorter than
+// both of the rows below it, like "Kernel IP routing table" in `route -n`.
+func isTitleLine(tab Table) bool {
+	return len(tab) >= 3 && len(tab[0]) < len(tab[1]) && len(tab[0]) < len(tab[2])
+}
+
+// getColumnByHeaderTolerant implements TolerantHeaders. Since a header that was
+// split into several cells shifts every header after it, all the headers
+// that were split must be known to find the columns after them: name and
+// multiWord. If the header row has more cells than the body even after
+// joining those, it returns an error rather than a misaligned column.
+func getColumnByHeaderTolerant(name string, tab Table, multiWord []string) (column Column, err error) {
+	if isTitleLine(tab) {
+		tab = tab[1:]
+	}
+	name = normalizeHeader(name)
+	if len(tab) < 1 || name == "" {
+		return column, nil
+	}
+	headers := logicalHeaders(tab[0], append([]string{name}, multiWord...))
+	width := 0
+	for _, row := range tab[1:] {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if len(headers) > width {
+		msg := "Couldn't align %d headers with %d columns, are any missing "
+		msg += "from the multi-word headers? %v"
+		return column, fmt.Errorf(msg, len(headers), width, headers)
+	}
+	for i, header := range headers {
+		if header == name {
+			return GetColumn(i, tab[1:]), nil
+		}
+	}
+	return column, nil
+}
+
 // GetColumnByHeader returns the body of a column with a header that is equal
 // to name (ignoring case differences). It is for developer ease and
 // future-proofing, as it doesn't rely on an index.
 func GetColumnByHeader(name string, tab Table) (column Column) {
-	// strIndexOf returns the index of a string in its slice
-	strIndexOf := func(str string, slc []string) int {
-		for i, sliceStr := range slc {
-			if strings.EqualFold(sliceStr, str) {
-				return i
-			}
-		}
-		return -1
+	// exact matching never fails
+	column, _ = GetColumnByHeaderMatching(name, tab, ExactHeaders)
+	return column
+}
+
+// GetColumnByHeaderMatching is like GetColumnByHeader, but lets the caller
+// choose how strictly headers are matched. With TolerantHeaders, multiWord
+// lists any other headers in the table that contain spaces, and an error is
+// returned if the headers can't be aligned with the body, see
+// getColumnByHeaderTolerant.
+func GetColumnByHeaderMatching(name string, tab Table, matching HeaderMatching, multiWord ...string) (column Column, err error) {
+	if matching == TolerantHeaders {
+		return getColumnByHeaderTolerant(name, tab, multiWord)
 	}
 	// if the table's empty, the column will be too
 	if len(tab) < 1 {
-		return column
+		return column, nil
 	}
-	// ensure that header is present in the headers
-	headerCol := -1
-	if headerCol = strIndexOf(name, tab[0]); headerCol == -1 {
-		return column
+	for i, header := range tab[0] {
+		if strings.EqualFold(header, name) {
+			return GetColumnNoHeader(i, tab), nil
+		}
 	}
-	return GetColumnNoHeader(headerCol, tab)
+	return column, nil
 }
 
 // StringPredicate is a function that filters a list of strings
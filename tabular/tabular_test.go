@@ -175,6 +175,73 @@ func TestGetColumnByHeader(t *testing.T) {
 	}
 }
 
+// header rows (and a row of their bodies) that GetColumnByHeader used to
+// misparse, as split by ProbabalisticSplit
+var (
+	routeTable = Table{
+		{"Kernel", "IP", "routing", "table"},
+		{"Destination", "Gateway", "Genmask", "Flags", "Metric", "Ref", "Use", "Iface"},
+		{"0.0.0.0", "192.0.2.1", "0.0.0.0", "UG", "0", "0", "0", "eth0"},
+	}
+	unitFileTable = Table{
+		{"UNIT", "FILE", "STATE", "PRESET"},
+		{"dev-hugepages.mount", "static", "-"},
+		{"sshd.service", "enabled", "enabled"},
+	}
+	// the header is missing, and the first row isn't a title
+	dfTable = Table{
+		{"Filesystem", "Size"},
+		{"Inodes", "10"},
+		{"/dev/sda", "20"},
+	}
+)
+
+func TestGetColumnByHeaderMatching(t *testing.T) {
+	t.Parallel()
+	inputs := []Table{
+		routeTable, routeTable, unitFileTable, unitFileTable, unitFileTable,
+		unitFileTable, unitFileTable, unitFileTable, outputTables[0],
+		outputTables[0], dfTable, dfTable, routeTable, unitFileTable,
+		unitFileTable, dfTable,
+	}
+	names := []string{
+		"Gateway", " iface ", "UNIT FILE", "unit   file", "STATE", "preset",
+		"STATE", "unit", "3", "", "Inodes", "size", "Gateway", "UNIT FILE",
+		"unit", "Inodes",
+	}
+	multiWords := [][]string{
+		nil, nil, nil, nil, {"UNIT FILE"}, {"unit file"}, nil, {"UNIT FILE"},
+		nil, nil, nil, nil, nil, nil, nil, nil,
+	}
+	matchings := []HeaderMatching{
+		TolerantHeaders, TolerantHeaders, TolerantHeaders, TolerantHeaders,
+		TolerantHeaders, TolerantHeaders, TolerantHeaders, TolerantHeaders,
+		TolerantHeaders, TolerantHeaders, TolerantHeaders, TolerantHeaders,
+		ExactHeaders, ExactHeaders, ExactHeaders, ExactHeaders,
+	}
+	outputs := [][]string{
+		{"192.0.2.1"}, {"eth0"}, {"dev-hugepages.mount", "sshd.service"},
+		{"dev-hugepages.mount", "sshd.service"}, {"static", "enabled"},
+		{"-", "enabled"}, {}, {}, {"3", "3"}, {}, {}, {"10", "20"}, {}, {},
+		{"dev-hugepages.mount", "sshd.service"}, {},
+	}
+	// the headers can't be aligned without knowing about "UNIT FILE"
+	errs := []bool{
+		false, false, false, false, false, false, true, false, false, false,
+		false, false, false, false, false, false,
+	}
+	for i := range inputs {
+		expected := outputs[i]
+		actual, err := GetColumnByHeaderMatching(names[i], inputs[i],
+			matchings[i], multiWords[i]...)
+		if (err != nil) != errs[i] {
+			t.Errorf("GetColumnByHeaderMatching(%q) returned error %v", names[i], err)
+		} else if !SliceEqual(expected, actual) {
+			pureFunctionError(t, names[i], expected, actual)
+		}
+	}
+}
+
 var testStrings = []string{
 	"test", "  testing", "01243894word10238", "aasdff", "drow", "esac", "fi",
 }